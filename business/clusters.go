@@ -0,0 +1,150 @@
+package business
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/kiali/k-charted/model"
+	"github.com/kiali/k-charted/prometheus"
+)
+
+// ClusterLabel is the label added to series fetched in all-clusters mode, holding the cluster name
+const ClusterLabel = "cluster"
+
+// DefaultClusterName is the name of the default cluster when config.ClusterName isn't set
+const DefaultClusterName = "default"
+
+// clusterTarget is a cluster which metrics are fetched from
+type clusterTarget struct {
+	// name is empty for the default cluster
+	name string
+	prom prometheus.ClientInterface
+}
+
+// ForCluster returns a service bound to a cluster declared in config.Clusters: dashboards are resolved from that cluster's
+// Kubernetes API and metrics are fetched from its Prometheus. Other settings are inherited from this service.
+// The name of the default cluster, unless declared in config.Clusters, returns this service.
+func (in *DashboardsService) ForCluster(name string) (*DashboardsService, error) {
+	in.clustersLock.Lock()
+	defer in.clustersLock.Unlock()
+	if svc, ok := in.clusters[name]; ok {
+		return svc, nil
+	}
+	for _, cluster := range in.config.Clusters {
+		if cluster.Name == name {
+			conf := in.config
+			conf.Prometheus = cluster.Prometheus
+			conf.Kubernetes = cluster.Kubernetes
+			conf.Clusters = nil
+			svc := &DashboardsService{config: conf, Logger: in.Logger}
+			if in.clusters == nil {
+				in.clusters = make(map[string]*DashboardsService)
+			}
+			in.clusters[name] = svc
+			return svc, nil
+		}
+	}
+	if name == in.clusterName() {
+		return in, nil
+	}
+	return nil, fmt.Errorf("unknown cluster: %s", name)
+}
+
+func (in *DashboardsService) clusterName() string {
+	if in.config.ClusterName != "" {
+		return in.config.ClusterName
+	}
+	return DefaultClusterName
+}
+
+// isDefaultClusterListed tells whether the default cluster is also declared in config.Clusters, either by name
+// or by Prometheus URL, in which case it must not be queried twice in all-clusters mode
+func (in *DashboardsService) isDefaultClusterListed() bool {
+	for _, cluster := range in.config.Clusters {
+		if cluster.Name == in.clusterName() || (cluster.Prometheus.URL != "" && cluster.Prometheus.URL == in.config.Prometheus.URL) {
+			return true
+		}
+	}
+	return false
+}
+
+// targets returns the clusters which metrics are fetched from: the default cluster, or in all-clusters mode the default cluster
+// and every configured cluster. Clients are bound to the queried namespace.
+func (in *DashboardsService) targets(cluster, namespace string) ([]clusterTarget, error) {
+	if cluster != model.AllClusters {
+		promClient, err := in.prom()
+		if err != nil {
+			return nil, err
+		}
+		return []clusterTarget{{prom: promClient.ForNamespace(namespace)}}, nil
+	}
+	targets := []clusterTarget{}
+	if !in.isDefaultClusterListed() {
+		promClient, err := in.prom()
+		if err != nil {
+			return nil, fmt.Errorf("cluster %s: %v", in.clusterName(), err)
+		}
+		targets = append(targets, clusterTarget{name: in.clusterName(), prom: promClient.ForNamespace(namespace)})
+	}
+	for _, cluster := range in.config.Clusters {
+		svc, err := in.ForCluster(cluster.Name)
+		if err != nil {
+			return nil, err
+		}
+		promClient, err := svc.prom()
+		if err != nil {
+			return nil, fmt.Errorf("cluster %s: %v", cluster.Name, err)
+		}
+		targets = append(targets, clusterTarget{name: cluster.Name, prom: promClient.ForNamespace(namespace)})
+	}
+	return targets, nil
+}
+
+// tagCluster adds the cluster label to series, when fetched in all-clusters mode
+func tagCluster(series []*model.SampleStream, cluster string) {
+	if cluster == "" {
+		return
+	}
+	for _, s := range series {
+		s.LabelSet[ClusterLabel] = cluster
+	}
+}
+
+// tagClusterHeatmaps is the same as tagCluster, for heatmaps
+func tagClusterHeatmaps(heatmaps []*model.Heatmap, cluster string) {
+	if cluster == "" {
+		return
+	}
+	for _, h := range heatmaps {
+		h.LabelSet[ClusterLabel] = cluster
+	}
+}
+
+// tagClusterSamples is the same as tagCluster, for the samples of instant charts
+func tagClusterSamples(samples []*model.Sample, cluster string) {
+	if cluster == "" {
+		return
+	}
+	for _, s := range samples {
+		s.LabelSet[ClusterLabel] = cluster
+	}
+}
+
+// mergeOptions returns the sorted union of options coming from several clusters
+func mergeOptions(options [][]string) []string {
+	if len(options) == 1 {
+		return options[0]
+	}
+	unique := make(map[string]bool)
+	merged := []string{}
+	for _, opts := range options {
+		for _, opt := range opts {
+			if !unique[opt] {
+				unique[opt] = true
+				merged = append(merged, opt)
+			}
+		}
+	}
+	sort.Strings(merged)
+	return merged
+}
@@ -0,0 +1,161 @@
+package business
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiali/k-charted/config/extconfig"
+	kmock "github.com/kiali/k-charted/kubernetes/mock"
+	"github.com/kiali/k-charted/model"
+	"github.com/kiali/k-charted/prometheus"
+	pmock "github.com/kiali/k-charted/prometheus/mock"
+)
+
+func setupClusters(service *DashboardsService, names ...string) (map[string]*kmock.ClientMock, map[string]*pmock.PromClientMock) {
+	k8sClients := make(map[string]*kmock.ClientMock)
+	promClients := make(map[string]*pmock.PromClientMock)
+	for _, name := range names {
+		service.config.Clusters = append(service.config.Clusters, extconfig.ClusterConfig{Name: name})
+		svc, _ := service.ForCluster(name)
+		k8sClients[name] = new(kmock.ClientMock)
+		promClients[name] = new(pmock.PromClientMock)
+		svc.k8sClient = k8sClients[name]
+		svc.promClient = promClients[name]
+	}
+	return k8sClients, promClients
+}
+
+func TestForCluster(t *testing.T) {
+	assert := assert.New(t)
+
+	service, _, _ := setupService()
+	service.config.Clusters = []extconfig.ClusterConfig{{Name: "east", Prometheus: extconfig.PrometheusConfig{URL: "http://prometheus.east"}}}
+
+	svc, err := service.ForCluster("east")
+	assert.Nil(err)
+	assert.Equal("http://prometheus.east", svc.config.Prometheus.URL)
+	assert.Equal("istio-system", svc.config.GlobalNamespace)
+	assert.Empty(svc.config.Clusters)
+
+	same, _ := service.ForCluster("east")
+	assert.True(svc == same)
+
+	_, err = service.ForCluster("west")
+	assert.NotNil(err)
+}
+
+func TestForClusterConcurrent(t *testing.T) {
+	assert := assert.New(t)
+
+	service, _, _ := setupService()
+	service.config.Clusters = []extconfig.ClusterConfig{{Name: "east"}}
+
+	services := make(chan *DashboardsService, 10)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			svc, _ := service.ForCluster("east")
+			services <- svc
+		}()
+	}
+	wg.Wait()
+	close(services)
+	first := <-services
+	for svc := range services {
+		assert.True(first == svc)
+	}
+}
+
+func TestGetDashboardForCluster(t *testing.T) {
+	assert := assert.New(t)
+
+	service, k8s, prom := setupService()
+	k8sClients, promClients := setupClusters(service, "east")
+	k8sClients["east"].On("GetDashboard", "my-namespace", "dashboard1").Return(fakeDashboard("1"), nil)
+
+	query := model.DashboardQuery{Namespace: "my-namespace", Cluster: "east"}
+	query.FillDefaults()
+	expectedLabels := "{namespace=\"my-namespace\"}"
+	promClients["east"].On("FetchRateRange", "my_metric_1_1", expectedLabels, "", &query.MetricsQuery).Return(pmock.FakeCounter(10))
+	promClients["east"].On("FetchHistogramRange", "my_metric_1_2", expectedLabels, "", &query.MetricsQuery).Return(pmock.FakeHistogram(11, 11))
+
+	dashboard, err := service.GetDashboard(query, "dashboard1")
+
+	assert.Nil(err)
+	assert.Len(dashboard.Charts, 2)
+	assert.NotContains(dashboard.Charts[0].Metrics[0].LabelSet, ClusterLabel)
+	k8s.AssertNotCalled(t, "GetDashboard", "my-namespace", "dashboard1")
+	prom.AssertNotCalled(t, "FetchRateRange", "my_metric_1_1", expectedLabels, "", &query.MetricsQuery)
+
+	query.Cluster = "west"
+	_, err = service.GetDashboard(query, "dashboard1")
+	assert.NotNil(err)
+}
+
+func TestGetDashboardAllClusters(t *testing.T) {
+	assert := assert.New(t)
+
+	service, k8s, prom := setupService()
+	_, promClients := setupClusters(service, "east", "west")
+	dashboard := fakeDashboard("1")
+	dashboard.Spec.Items = dashboard.Spec.Items[:1]
+	k8s.On("GetDashboard", "my-namespace", "dashboard1").Return(dashboard, nil)
+
+	query := model.DashboardQuery{Namespace: "my-namespace", Cluster: model.AllClusters}
+	query.FillDefaults()
+	expectedLabels := "{namespace=\"my-namespace\"}"
+	prom.On("FetchRateRange", "my_metric_1_1", expectedLabels, "", &query.MetricsQuery).Return(pmock.FakeCounter(5))
+	promClients["east"].On("FetchRateRange", "my_metric_1_1", expectedLabels, "", &query.MetricsQuery).Return(pmock.FakeCounter(10))
+	promClients["west"].On("FetchRateRange", "my_metric_1_1", expectedLabels, "", &query.MetricsQuery).Return(prometheus.Metric{Err: errors.New("unavailable")})
+
+	result, err := service.GetDashboard(query, "dashboard1")
+
+	assert.Nil(err)
+	assert.Len(result.Charts, 1)
+	assert.Len(result.Charts[0].Metrics, 2)
+	assert.Equal(DefaultClusterName, result.Charts[0].Metrics[0].LabelSet[ClusterLabel])
+	assert.Equal("east", result.Charts[0].Metrics[1].LabelSet[ClusterLabel])
+	assert.Equal("cluster west: error in metric my_metric_1_1: unavailable", result.Charts[0].Error)
+}
+
+func TestAllClustersTargets(t *testing.T) {
+	assert := assert.New(t)
+
+	service, _, _ := setupService()
+	service.config.ClusterName = "main"
+	service.config.Prometheus.URL = "http://prometheus.main"
+	setupClusters(service, "east")
+
+	targets, err := service.targets(model.AllClusters, "my-namespace")
+	assert.Nil(err)
+	assert.Len(targets, 2)
+	assert.Equal("main", targets[0].name)
+	assert.Equal("east", targets[1].name)
+
+	// The default cluster can be selected by name
+	svc, err := service.ForCluster("main")
+	assert.Nil(err)
+	assert.True(svc == service)
+
+	// The default cluster is listed with another name, but the same Prometheus
+	service.config.Clusters = append(service.config.Clusters, extconfig.ClusterConfig{Name: "primary", Prometheus: service.config.Prometheus})
+	svc, _ = service.ForCluster("primary")
+	svc.promClient = new(pmock.PromClientMock)
+	targets, err = service.targets(model.AllClusters, "my-namespace")
+	assert.Nil(err)
+	assert.Len(targets, 2)
+	assert.Equal("east", targets[0].name)
+	assert.Equal("primary", targets[1].name)
+}
+
+func TestMergeOptions(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal([]string{"b", "a"}, mergeOptions([][]string{{"b", "a"}}))
+	assert.Equal([]string{"a", "b", "c"}, mergeOptions([][]string{{"b", "a"}, {"c", "b"}}))
+}
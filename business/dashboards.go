@@ -1,14 +1,20 @@
 package business
 
 import (
+	"context"
 	"fmt"
+	"math"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
+
+	pmodel "github.com/prometheus/common/model"
 
 	"github.com/kiali/k-charted/config"
 	"github.com/kiali/k-charted/kubernetes"
-	"github.com/kiali/k-charted/kubernetes/v1alpha1"
+	"github.com/kiali/k-charted/kubernetes/v1beta1"
 	"github.com/kiali/k-charted/log"
 	"github.com/kiali/k-charted/model"
 	"github.com/kiali/k-charted/prometheus"
@@ -18,10 +24,14 @@ const defaultNamespaceLabel = "namespace"
 
 // DashboardsService deals with fetching dashboards from k8s client
 type DashboardsService struct {
-	promClient prometheus.ClientInterface
-	k8sClient  kubernetes.ClientInterface
-	config     config.Config
-	Logger     log.SafeAdapter
+	promClient  prometheus.ClientInterface
+	k8sClient   kubernetes.ClientInterface
+	filesClient kubernetes.ClientInterface
+	// clusters holds the services bound to other clusters, see ForCluster. It is guarded by clustersLock.
+	clusters     map[string]*DashboardsService
+	clustersLock sync.Mutex
+	config       config.Config
+	Logger       log.SafeAdapter
 }
 
 // NewDashboardsService initializes this business service
@@ -47,7 +57,15 @@ func (in *DashboardsService) prom() (prometheus.ClientInterface, error) {
 func (in *DashboardsService) k8s() (kubernetes.ClientInterface, error) {
 	// Lazy init
 	if in.k8sClient == nil {
-		client, err := kubernetes.NewClient()
+		if in.config.Kubernetes.CacheEnabled {
+			client, err := kubernetes.GetCachedClient(in.config.Kubernetes)
+			if err != nil {
+				return nil, fmt.Errorf("cannot initialize cached Kubernetes Client: %v", err)
+			}
+			in.k8sClient = client
+			return in.k8sClient, nil
+		}
+		client, err := kubernetes.NewClientWithConfig(in.config.Kubernetes)
 		if err != nil {
 			return nil, fmt.Errorf("cannot initialize Kubernetes Client: %v", err)
 		}
@@ -56,96 +74,102 @@ func (in *DashboardsService) k8s() (kubernetes.ClientInterface, error) {
 	return in.k8sClient, nil
 }
 
-// k8sGetDashboard wraps KialiMonitoringInterface.GetDashboard with client creation & error handling
-func (in *DashboardsService) k8sGetDashboard(namespace, template string) (*v1alpha1.MonitoringDashboard, error) {
-	client, err := in.k8s()
+// loadRawDashboardResource looks for a dashboard in every source, by order of precedence. It returns the dashboard and the name of the source it came from.
+func (in *DashboardsService) loadRawDashboardResource(namespace, template string) (*v1beta1.MonitoringDashboard, string, error) {
+	// There is an override mechanism with dashboards: by default, dashboards can be provided in Kiali namespace,
+	// and can be overriden in app namespace. More generally, the first source providing the dashboard wins.
+	layers, err := in.layers()
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	in.Logger.Tracef("load k8s dashboard '%s' in namespace '%s'", template, namespace)
-	return client.GetDashboard(namespace, template)
-}
-
-// k8sGetDashboards wraps KialiMonitoringInterface.GetDashboards with client creation & error handling
-func (in *DashboardsService) k8sGetDashboards(namespace string) ([]v1alpha1.MonitoringDashboard, error) {
-	client, err := in.k8s()
-	if err != nil {
-		return nil, err
-	}
-	in.Logger.Tracef("load all k8s dashboards in namespace '%s'", namespace)
-	return client.GetDashboards(namespace)
-}
-
-func (in *DashboardsService) loadRawDashboardResource(namespace, template string) (*v1alpha1.MonitoringDashboard, error) {
-	// There is an override mechanism with dashboards: default dashboards can be provided in Kiali namespace,
-	// and can be overriden in app namespace.
-	// So we look for the one in app namespace first, and only if not found fallback to the one in istio-system.
-	dashboard, err := in.k8sGetDashboard(namespace, template)
-	if err != nil && in.config.GlobalNamespace != "" {
-		dashboard, err = in.k8sGetDashboard(in.config.GlobalNamespace, template)
+	err = fmt.Errorf("dashboard %s not found: no dashboard source configured", template)
+	for _, layer := range layers {
+		var client kubernetes.ClientInterface
+		client, err = layer.client()
 		if err != nil {
-			return nil, err
+			continue
+		}
+		ns := layer.resolveNamespace(namespace)
+		in.Logger.Tracef("load dashboard '%s' in namespace '%s' from source '%s'", template, ns, layer.name)
+		var dashboard *v1beta1.MonitoringDashboard
+		dashboard, err = client.GetDashboard(ns, template)
+		if err == nil {
+			return dashboard, layer.name, nil
 		}
 	}
-	return dashboard, err
+	return nil, "", err
 }
 
-func (in *DashboardsService) loadRawDashboardResources(namespace string) (map[string]v1alpha1.MonitoringDashboard, error) {
-	all := make(map[string]v1alpha1.MonitoringDashboard)
-
-	// From global namespace
-	if in.config.GlobalNamespace != "" {
-		dashboards, err := in.k8sGetDashboards(in.config.GlobalNamespace)
-		if err != nil {
-			return nil, err
-		}
-		for _, d := range dashboards {
-			all[d.Name] = d
-		}
+// loadRawDashboardResources loads all dashboards from every source. On name conflicts, the source with higher precedence wins.
+func (in *DashboardsService) loadRawDashboardResources(namespace string) (map[string]v1beta1.MonitoringDashboard, error) {
+	layers, err := in.layers()
+	if err != nil {
+		return nil, err
 	}
-
-	// From specific namespace
-	if namespace != in.config.GlobalNamespace {
-		dashboards, err := in.k8sGetDashboards(namespace)
-		if err != nil {
-			return nil, err
+	all := make(map[string]v1beta1.MonitoringDashboard)
+	loaded := make(map[string]bool)
+	success := false
+	// Iterate from lowest to highest precedence, so that overrides replace defaults
+	for i := len(layers) - 1; i >= 0; i-- {
+		layer := layers[i]
+		ns := layer.resolveNamespace(namespace)
+		if loaded[layer.backend+"/"+ns] {
+			continue
 		}
-		for _, d := range dashboards {
-			all[d.Name] = d
+		loaded[layer.backend+"/"+ns] = true
+		client, err := layer.client()
+		if err == nil {
+			in.Logger.Tracef("load all dashboards in namespace '%s' from source '%s'", ns, layer.name)
+			var dashboards []v1beta1.MonitoringDashboard
+			dashboards, err = client.GetDashboards(ns)
+			if err == nil {
+				success = true
+				for _, d := range dashboards {
+					all[d.Name] = d
+				}
+				continue
+			}
 		}
+		in.Logger.Warningf("cannot load dashboards in namespace '%s' from source '%s': %v", ns, layer.name, err)
+	}
+	if !success && len(layers) > 0 {
+		return nil, fmt.Errorf("no dashboard source could be loaded for namespace %s", namespace)
 	}
-
 	return all, nil
 }
 
-func (in *DashboardsService) loadAndResolveDashboardResource(namespace, template string, loaded map[string]bool) (*v1alpha1.MonitoringDashboard, error) {
+func (in *DashboardsService) loadAndResolveDashboardResource(namespace, template string, loaded map[string]bool) (*v1beta1.MonitoringDashboard, string, error) {
 	// Circular dependency check
 	if _, ok := loaded[template]; ok {
-		return nil, fmt.Errorf("cannot load dashboard %s due to circular dependency detected. Already loaded dependencies: %v", template, loaded)
+		return nil, "", fmt.Errorf("cannot load dashboard %s due to circular dependency detected. Already loaded dependencies: %v", template, loaded)
 	}
 	loaded[template] = true
-	dashboard, err := in.loadRawDashboardResource(namespace, template)
+	dashboard, source, err := in.loadRawDashboardResource(namespace, template)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	err = in.resolveReferences(namespace, dashboard, loaded)
-	return dashboard, err
+	return dashboard, source, err
 }
 
 // resolveReferences resolves the composition mechanism that allows to reference a dashboard from another one
-func (in *DashboardsService) resolveReferences(namespace string, dashboard *v1alpha1.MonitoringDashboard, loaded map[string]bool) error {
-	resolved := []v1alpha1.MonitoringDashboardItem{}
+func (in *DashboardsService) resolveReferences(namespace string, dashboard *v1beta1.MonitoringDashboard, loaded map[string]bool) error {
+	resolved := []v1beta1.MonitoringDashboardItem{}
 	for _, item := range dashboard.Spec.Items {
 		reference := strings.TrimSpace(item.Include)
 		if reference != "" {
 			// reference can point to a whole dashboard (ex: microprofile-1.0) or a chart within a dashboard (ex: microprofile-1.0$Thread count)
 			parts := strings.Split(reference, "$")
 			dashboardRefName := parts[0]
-			composedDashboard, err := in.loadAndResolveDashboardResource(namespace, dashboardRefName, loaded)
+			composedDashboard, _, err := in.loadAndResolveDashboardResource(namespace, dashboardRefName, loaded)
 			if err != nil {
 				return err
 			}
+			mergeVariables(dashboard, composedDashboard.Spec.Variables)
 			for _, item2 := range composedDashboard.Spec.Items {
+				if item2.Chart == nil {
+					continue
+				}
 				if len(parts) > 1 {
 					// Reference a specific chart
 					if item2.Chart.Name == parts[1] {
@@ -157,7 +181,7 @@ func (in *DashboardsService) resolveReferences(namespace string, dashboard *v1al
 					resolved = append(resolved, item2)
 				}
 			}
-		} else {
+		} else if item.Chart != nil {
 			resolved = append(resolved, item)
 		}
 	}
@@ -165,18 +189,58 @@ func (in *DashboardsService) resolveReferences(namespace string, dashboard *v1al
 	return nil
 }
 
-// GetDashboard returns a dashboard filled-in with target data
+// mergeVariables adds variables used by included charts, unless the including dashboard already declares them
+func mergeVariables(dashboard *v1beta1.MonitoringDashboard, variables []v1beta1.MonitoringDashboardVariable) {
+	for _, variable := range variables {
+		declared := false
+		for _, existing := range dashboard.Spec.Variables {
+			if existing.Name == variable.Name {
+				declared = true
+				break
+			}
+		}
+		if !declared {
+			dashboard.Spec.Variables = append(dashboard.Spec.Variables, variable)
+		}
+	}
+}
+
+// GetDashboard returns a dashboard filled-in with target data, see GetDashboardWithContext
 func (in *DashboardsService) GetDashboard(params model.DashboardQuery, template string) (*model.MonitoringDashboard, error) {
-	promClient, err := in.prom()
+	return in.GetDashboardWithContext(context.Background(), params, template)
+}
+
+// GetDashboardWithContext returns a dashboard filled-in with target data. Pending queries are aborted when the context is done.
+// When a cluster is set in params, it is delegated to the service bound to that cluster (see ForCluster).
+// In all-clusters mode, the dashboard is resolved from the default cluster and metrics are fetched from the default cluster
+// and every configured cluster, each series being tagged with the cluster label.
+func (in *DashboardsService) GetDashboardWithContext(ctx context.Context, params model.DashboardQuery, template string) (*model.MonitoringDashboard, error) {
+	if params.Cluster != "" && params.Cluster != model.AllClusters {
+		svc, err := in.ForCluster(params.Cluster)
+		if err != nil {
+			return nil, err
+		}
+		params.Cluster = ""
+		return svc.GetDashboardWithContext(ctx, params, template)
+	}
+	if err := prometheus.Selector(params.LabelsFilters).Validate(); err != nil {
+		return nil, fmt.Errorf("invalid labels filters: %v", err)
+	}
+	dashboardCtx, cancel := withOptionalTimeout(ctx, in.config.DashboardTimeout)
+	defer cancel()
+	targets, err := in.targets(params.Cluster, params.Namespace)
 	if err != nil {
 		return nil, err
 	}
-	dashboard, err := in.loadAndResolveDashboardResource(params.Namespace, template, map[string]bool{})
+	dashboard, source, err := in.loadAndResolveDashboardResource(params.Namespace, template, map[string]bool{})
 	if err != nil {
 		return nil, err
 	}
 
-	filters := in.buildLabels(params.Namespace, params.LabelsFilters)
+	variables, values := in.resolveVariables(dashboardCtx, dashboard.Spec.Variables, params, targets)
+	applyVariables(dashboard, values)
+
+	filters := in.buildLabels(params.Namespace, substituteLabelsFilters(params.LabelsFilters, values))
 	aggLabels := append(params.AdditionalLabels, model.ConvertAggregations(dashboard.Spec)...)
 	if len(aggLabels) == 0 {
 		// Prevent null in json
@@ -188,47 +252,25 @@ func (in *DashboardsService) GetDashboard(params model.DashboardQuery, template
 	filledCharts := make([]model.Chart, len(dashboard.Spec.Items))
 
 	for i, item := range dashboard.Spec.Items {
-		go func(idx int, chart v1alpha1.MonitoringDashboardChart) {
+		go func(idx int, chart *v1beta1.MonitoringDashboardChart) {
 			defer wg.Done()
-			conversionParams := model.ConversionParams{Scale: 1.0, SortLabel: chart.SortLabel, SortLabelParseAs: chart.SortLabelParseAs}
-			if chart.UnitScale != 0.0 {
-				conversionParams.Scale = chart.UnitScale
-			}
-			// Group by labels is concat of what is defined in CR + what is passed as parameters
-			byLabels := append(chart.GroupLabels, params.ByLabels...)
-			if len(chart.SortLabel) > 0 {
-				// We also need to group by the label used for sorting, if not explicitly present
-				present := false
-				for _, lbl := range byLabels {
-					if lbl == chart.SortLabel {
-						present = true
-						break
-					}
-				}
-				if !present {
-					byLabels = append(byLabels, chart.SortLabel)
-					// Mark the sort label to not be kept during conversion
-					conversionParams.RemoveSortLabel = true
-				}
-			}
-			grouping := strings.Join(byLabels, ",")
-
-			filledCharts[idx] = model.ConvertChart(chart)
-			metrics := chart.GetMetrics()
-			for _, ref := range metrics {
-				if chart.DataType == v1alpha1.Raw {
-					aggregator := params.RawDataAggregator
-					if chart.Aggregator != "" {
-						aggregator = chart.Aggregator
-					}
-					metric := promClient.FetchRange(ref.MetricName, filters, grouping, aggregator, &params.MetricsQuery)
-					filledCharts[idx].FillMetric(ref, metric, conversionParams)
-				} else if chart.DataType == v1alpha1.Rate {
-					metric := promClient.FetchRateRange(ref.MetricName, filters, grouping, &params.MetricsQuery)
-					filledCharts[idx].FillMetric(ref, metric, conversionParams)
+			timeout := in.chartTimeout(chart)
+			chartCtx, cancel := withOptionalTimeout(dashboardCtx, timeout)
+			defer cancel()
+			// Don't wait for queries beyond the deadline, in case they don't honor the context
+			result := make(chan model.Chart, 1)
+			go func() {
+				result <- in.fillChart(chartCtx, chart, params, filters, targets)
+			}()
+			select {
+			case filled := <-result:
+				filledCharts[idx] = filled
+			case <-chartCtx.Done():
+				filledCharts[idx] = model.ConvertChart(*chart)
+				if dashboardCtx.Err() == context.DeadlineExceeded {
+					filledCharts[idx].Error = fmt.Sprintf("timeout: dashboard queries took longer than %v", in.config.DashboardTimeout)
 				} else {
-					histo := promClient.FetchHistogramRange(ref.MetricName, filters, grouping, &params.MetricsQuery)
-					filledCharts[idx].FillHistogram(ref, histo, conversionParams)
+					filledCharts[idx].Error = fmt.Sprintf("timeout: chart queries took longer than %v", timeout)
 				}
 			}
 		}(i, item.Chart)
@@ -249,14 +291,201 @@ func (in *DashboardsService) GetDashboard(params model.DashboardQuery, template
 	}()
 
 	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	return &model.MonitoringDashboard{
 		Title:         dashboard.Spec.Title,
+		Source:        source,
 		Charts:        filledCharts,
 		Aggregations:  aggLabels,
 		ExternalLinks: externalLinks,
+		Variables:     variables,
 	}, nil
 }
 
+// chartTimeout returns the time budget of a chart, from the CR or else from config. Zero means no timeout.
+func (in *DashboardsService) chartTimeout(chart *v1beta1.MonitoringDashboardChart) time.Duration {
+	if chart.Timeout != "" {
+		if timeout, err := time.ParseDuration(chart.Timeout); err == nil {
+			return timeout
+		}
+		in.Logger.Warningf("invalid timeout %s in chart %s, using default", chart.Timeout, chart.Name)
+	}
+	return in.config.ChartTimeout
+}
+
+// roundingPrecision returns the precision of the rounding option of a chart, or zero when rounding is disabled
+func (in *DashboardsService) roundingPrecision(chart *v1beta1.MonitoringDashboardChart) float64 {
+	if chart.Rounding.Precision == v1beta1.RoundingNone {
+		return 0
+	}
+	precision, err := strconv.ParseFloat(chart.Rounding.Precision, 64)
+	if err != nil || !(precision > 0) || math.IsInf(precision, 0) {
+		in.Logger.Warningf("invalid rounding precision %s in chart %s, using default", chart.Rounding.Precision, chart.Name)
+		return prometheus.DefaultRounding
+	}
+	return precision
+}
+
+// chartMaxDataPoints returns the lowest limit of points per series among the chart and the dashboard query, zero for none
+func chartMaxDataPoints(chart *v1beta1.MonitoringDashboardChart, params model.DashboardQuery) int {
+	if chart.MaxDataPoints > 0 && (params.MaxDataPoints <= 0 || chart.MaxDataPoints < params.MaxDataPoints) {
+		return chart.MaxDataPoints
+	}
+	return params.MaxDataPoints
+}
+
+// limitDataPoints raises the step of a range query so that series have at most maxDataPoints points, and the rate interval
+// so that rates cover whole steps. The step is kept to whole seconds, and the range start is aligned on it.
+func limitDataPoints(q *prometheus.MetricsQuery, maxDataPoints int) {
+	intervals := maxDataPoints - 1
+	if intervals < 1 {
+		intervals = 1
+	}
+	step := q.End.Sub(q.Start) / time.Duration(intervals)
+	// Round up to the second
+	step = ((step + time.Second - 1) / time.Second) * time.Second
+	if step <= q.Step {
+		return
+	}
+	q.Step = step
+	stepInSecs := int64(step.Seconds())
+	q.Start = time.Unix((q.Start.Unix()/stepInSecs)*stepInSecs, 0)
+	// Rate intervals made of several units, such as 1h30m, are left as is
+	if interval, err := pmodel.ParseDuration(q.RateInterval); err == nil && time.Duration(interval) < step {
+		q.RateInterval = pmodel.Duration(step).String()
+	}
+}
+
+func withOptionalTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// fillChart runs the queries of a chart, on every target cluster
+func (in *DashboardsService) fillChart(ctx context.Context, chart *v1beta1.MonitoringDashboardChart, params model.DashboardQuery, filters string, targets []clusterTarget) model.Chart {
+	conversionParams := model.ConversionParams{Scale: 1.0}
+	if chart.Sort != nil {
+		conversionParams.SortLabel = chart.Sort.Label
+		conversionParams.SortLabelParseAs = string(chart.Sort.ParseAs)
+	}
+	if chart.UnitScale != 0.0 {
+		conversionParams.Scale = chart.UnitScale
+	}
+	// Values are rounded in queries, unless requested on conversion
+	metricsQuery := params.MetricsQuery
+	if chart.Rounding != nil {
+		precision := in.roundingPrecision(chart)
+		if chart.Rounding.ClientSide {
+			conversionParams.Precision = precision
+			precision = 0
+		}
+		metricsQuery.Rounding = &precision
+	}
+	// Charts of current values only need instant queries, at the end of the time range
+	metricsQuery.Instant = chart.ChartType.IsInstant()
+	if maxDataPoints := chartMaxDataPoints(chart, params); maxDataPoints > 0 && !metricsQuery.Instant {
+		limitDataPoints(&metricsQuery, maxDataPoints)
+		// Series can still exceed the limit by a point, as the range start is aligned on the step
+		conversionParams.MaxDataPoints = maxDataPoints
+	}
+	// Group by labels is concat of what is defined in CR + what is passed as parameters
+	byLabels := append(chart.Query.GroupLabels, params.ByLabels...)
+	if len(conversionParams.SortLabel) > 0 {
+		// We also need to group by the label used for sorting, if not explicitly present
+		present := false
+		for _, lbl := range byLabels {
+			if lbl == conversionParams.SortLabel {
+				present = true
+				break
+			}
+		}
+		if !present {
+			byLabels = append(byLabels, conversionParams.SortLabel)
+			// Mark the sort label to not be kept during conversion
+			conversionParams.RemoveSortLabel = true
+		}
+	}
+	grouping := strings.Join(byLabels, ",")
+
+	// Queries run concurrently, bounded by the Prometheus client queries pool; results are then filled in order
+	type fetched struct {
+		metric prometheus.Metric
+		histo  prometheus.Histogram
+	}
+	results := make([][]fetched, len(targets))
+	wg := sync.WaitGroup{}
+	for t, target := range targets {
+		results[t] = make([]fetched, len(chart.Metrics))
+		for m, ref := range chart.Metrics {
+			wg.Add(1)
+			go func(result *fetched, prom prometheus.ClientInterface, ref v1beta1.MonitoringDashboardMetric) {
+				defer wg.Done()
+				if chart.Query.DataType == v1beta1.Raw {
+					aggregator := params.RawDataAggregator
+					if chart.Query.Aggregator != "" {
+						aggregator = string(chart.Query.Aggregator)
+					}
+					result.metric = prom.FetchRange(ctx, ref.MetricName, filters, grouping, aggregator, &metricsQuery)
+				} else if chart.Query.DataType == v1beta1.Rate {
+					result.metric = prom.FetchRateRange(ctx, ref.MetricName, filters, grouping, &metricsQuery)
+				} else if chart.Query.DataType == v1beta1.Expr {
+					result.metric = prom.FetchExprRange(ctx, ref.Expr, filters, grouping, &metricsQuery)
+				} else if chart.Query.DataType == v1beta1.Summary {
+					result.histo = prom.FetchSummaryRange(ctx, ref.MetricName, filters, grouping, &metricsQuery)
+				} else if chart.ChartType == v1beta1.ChartTypeHeatmap {
+					result.metric = prom.FetchHistogramBuckets(ctx, ref.MetricName, filters, grouping, &metricsQuery)
+				} else {
+					result.histo = fetchHistogram(ctx, prom, chart.Query.HistogramMode, ref.MetricName, filters, grouping, &metricsQuery)
+				}
+			}(&results[t][m], target.prom, ref)
+		}
+	}
+	wg.Wait()
+
+	filled := model.ConvertChart(*chart)
+	for t, target := range targets {
+		from, fromHeatmaps, fromVector, previousError := len(filled.Metrics), len(filled.Heatmaps), len(filled.Vector), filled.Error
+		for m, ref := range chart.Metrics {
+			if results[t][m].histo != nil {
+				filled.FillHistogram(ref, results[t][m].histo, conversionParams)
+			} else if chart.ChartType == v1beta1.ChartTypeHeatmap {
+				filled.FillHeatmap(ref, results[t][m].metric, conversionParams)
+			} else {
+				filled.FillMetric(ref, results[t][m].metric, conversionParams)
+			}
+		}
+		tagCluster(filled.Metrics[from:], target.name)
+		tagClusterHeatmaps(filled.Heatmaps[fromHeatmaps:], target.name)
+		tagClusterSamples(filled.Vector[fromVector:], target.name)
+		if target.name != "" && filled.Error != previousError {
+			filled.Error = fmt.Sprintf("cluster %s: %s", target.name, filled.Error)
+		}
+	}
+	return filled
+}
+
+// fetchHistogram fetches classic or native histograms according to the chart mode. In auto mode, native histograms are
+// queried when no classic series is found.
+func fetchHistogram(ctx context.Context, prom prometheus.ClientInterface, mode v1beta1.HistogramMode, metricName, labels, grouping string, q *prometheus.MetricsQuery) prometheus.Histogram {
+	if mode == v1beta1.HistogramNative {
+		return prom.FetchNativeHistogramRange(ctx, metricName, labels, grouping, q)
+	}
+	histo := prom.FetchHistogramRange(ctx, metricName, labels, grouping, q)
+	if mode == v1beta1.HistogramClassic || len(histo) == 0 || ctx.Err() != nil {
+		return histo
+	}
+	for _, metric := range histo {
+		if metric.Err != nil || len(metric.Matrix) > 0 || len(metric.Vector) > 0 {
+			return histo
+		}
+	}
+	return prom.FetchNativeHistogramRange(ctx, metricName, labels, grouping, q)
+}
+
 // SearchExplicitDashboards will check annotations of all supplied pods to extract a unique list of dashboards
 //	Accepted annotations are "kiali.io/runtimes" and "kiali.io/dashboards"
 func (in *DashboardsService) SearchExplicitDashboards(namespace string, pods []model.Pod) []model.Runtime {
@@ -269,13 +498,13 @@ func (in *DashboardsService) SearchExplicitDashboards(namespace string, pods []m
 }
 
 func (in *DashboardsService) buildRuntimesList(namespace string, templatesNames []string) []model.Runtime {
-	dashboards := make([]*v1alpha1.MonitoringDashboard, len(templatesNames))
+	dashboards := make([]*v1beta1.MonitoringDashboard, len(templatesNames))
 	wg := sync.WaitGroup{}
 	wg.Add(len(templatesNames))
 	for idx, template := range templatesNames {
 		go func(i int, tpl string) {
 			defer wg.Done()
-			dashboard, err := in.loadRawDashboardResource(namespace, tpl)
+			dashboard, _, err := in.loadRawDashboardResource(namespace, tpl)
 			if err != nil {
 				in.Logger.Errorf("cannot get dashboard %s in namespace %s. Error was: %v", tpl, namespace, err)
 			} else {
@@ -296,30 +525,40 @@ func (in *DashboardsService) buildRuntimesList(namespace string, templatesNames
 	return runtimes
 }
 
-func (in *DashboardsService) fetchMetricNames(namespace string, labelsFilters map[string]string) []string {
+func (in *DashboardsService) fetchMetricNames(ctx context.Context, namespace string, labelsFilters []prometheus.LabelMatcher) []string {
 	promClient, err := in.prom()
 	if err != nil {
 		return []string{}
 	}
 
 	labels := in.buildLabels(namespace, labelsFilters)
-	metrics, err := promClient.GetMetricsForLabels([]string{labels})
+	metrics, err := promClient.ForNamespace(namespace).GetMetricsForLabels(ctx, []string{labels})
 	if err != nil {
 		in.Logger.Errorf("runtimes discovery failed, cannot load metrics for labels: %s. Error was: %v", labels, err)
 	}
 	return metrics
 }
 
-// DiscoverDashboards tries to discover dashboards based on existing metrics
+// DiscoverDashboards tries to discover dashboards based on existing metrics, matching labels filters for equality.
+// See DiscoverDashboardsWithContext.
 func (in *DashboardsService) DiscoverDashboards(namespace string, labelsFilters map[string]string) []model.Runtime {
-	in.Logger.Tracef("starting runtimes discovery on namespace %s with filters [%v]", namespace, labelsFilters)
+	return in.DiscoverDashboardsWithContext(context.Background(), namespace, prometheus.EqualMatchers(labelsFilters))
+}
+
+// DiscoverDashboardsWithContext tries to discover dashboards based on existing metrics. The metrics query is aborted when the context is done.
+func (in *DashboardsService) DiscoverDashboardsWithContext(ctx context.Context, namespace string, labelsFilters []prometheus.LabelMatcher) []model.Runtime {
+	in.Logger.Tracef("starting runtimes discovery on namespace %s with filters %v", namespace, prometheus.Selector(labelsFilters))
+	if err := prometheus.Selector(labelsFilters).Validate(); err != nil {
+		in.Logger.Errorf("runtimes discovery failed, invalid labels filters: %v", err)
+		return []model.Runtime{}
+	}
 
 	var metrics []string
 	wg := sync.WaitGroup{}
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		metrics = in.fetchMetricNames(namespace, labelsFilters)
+		metrics = in.fetchMetricNames(ctx, namespace, labelsFilters)
 	}()
 
 	allDashboards, err := in.loadRawDashboardResources(namespace)
@@ -332,10 +571,10 @@ func (in *DashboardsService) DiscoverDashboards(namespace string, labelsFilters
 	return runDiscoveryMatcher(metrics, allDashboards)
 }
 
-func runDiscoveryMatcher(metrics []string, allDashboards map[string]v1alpha1.MonitoringDashboard) []model.Runtime {
+func runDiscoveryMatcher(metrics []string, allDashboards map[string]v1beta1.MonitoringDashboard) []model.Runtime {
 	// In all dashboards, finds the ones that match the metrics set
 	// We must exclude from the results included dashboards when both the including and the included dashboards are matching
-	runtimesMap := make(map[string]*v1alpha1.MonitoringDashboard)
+	runtimesMap := make(map[string]*v1beta1.MonitoringDashboard)
 	for _, d := range allDashboards {
 		dashboard := d // sticky reference
 		matchReference := strings.TrimSpace(dashboard.Spec.DiscoverOn)
@@ -367,7 +606,7 @@ func runDiscoveryMatcher(metrics []string, allDashboards map[string]v1alpha1.Mon
 	return runtimes
 }
 
-func addDashboardToRuntimes(dashboard *v1alpha1.MonitoringDashboard, runtimes []model.Runtime) []model.Runtime {
+func addDashboardToRuntimes(dashboard *v1beta1.MonitoringDashboard, runtimes []model.Runtime) []model.Runtime {
 	runtime := dashboard.Spec.Runtime
 	ref := model.DashboardRef{
 		Template: dashboard.Name,
@@ -391,15 +630,11 @@ func addDashboardToRuntimes(dashboard *v1alpha1.MonitoringDashboard, runtimes []
 	return runtimes
 }
 
-func (in *DashboardsService) buildLabels(namespace string, labelsFilters map[string]string) string {
+func (in *DashboardsService) buildLabels(namespace string, labelsFilters []prometheus.LabelMatcher) string {
 	namespaceLabel := in.config.NamespaceLabel
 	if namespaceLabel == "" {
 		namespaceLabel = defaultNamespaceLabel
 	}
-	labels := fmt.Sprintf(`{%s="%s"`, namespaceLabel, namespace)
-	for k, v := range labelsFilters {
-		labels += fmt.Sprintf(`,%s="%s"`, k, v)
-	}
-	labels += "}"
-	return labels
+	selector := prometheus.Selector{{Name: namespaceLabel, Type: prometheus.MatchEqual, Value: namespace}}
+	return append(selector, labelsFilters...).String()
 }
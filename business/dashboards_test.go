@@ -1,18 +1,22 @@
 package business
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"testing"
+	"time"
 
+	pmodel "github.com/prometheus/common/model"
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/kiali/k-charted/config"
 	kmock "github.com/kiali/k-charted/kubernetes/mock"
-	"github.com/kiali/k-charted/kubernetes/v1alpha1"
+	"github.com/kiali/k-charted/kubernetes/v1beta1"
 	"github.com/kiali/k-charted/log"
 	"github.com/kiali/k-charted/model"
+	"github.com/kiali/k-charted/prometheus"
 	"github.com/kiali/k-charted/prometheus/mock"
 	pmock "github.com/kiali/k-charted/prometheus/mock"
 )
@@ -43,8 +47,8 @@ func TestGetDashboard(t *testing.T) {
 	expectedLabels := "{namespace=\"my-namespace\",APP=\"my-app\"}"
 	query := model.DashboardQuery{
 		Namespace: "my-namespace",
-		LabelsFilters: map[string]string{
-			"APP": "my-app",
+		LabelsFilters: []prometheus.LabelMatcher{
+			{Name: "APP", Type: prometheus.MatchEqual, Value: "my-app"},
 		},
 		AdditionalLabels: []model.Aggregation{
 			{
@@ -87,8 +91,8 @@ func TestGetDashboardFromKialiNamespace(t *testing.T) {
 	expectedLabels := "{namespace=\"my-namespace\",APP=\"my-app\"}"
 	query := model.DashboardQuery{
 		Namespace: "my-namespace",
-		LabelsFilters: map[string]string{
-			"APP": "my-app",
+		LabelsFilters: []prometheus.LabelMatcher{
+			{Name: "APP", Type: prometheus.MatchEqual, Value: "my-app"},
 		},
 	}
 	query.FillDefaults()
@@ -100,20 +104,79 @@ func TestGetDashboardFromKialiNamespace(t *testing.T) {
 	assert.Nil(err)
 	k8s.AssertNumberOfCalls(t, "GetDashboard", 2)
 	assert.Equal("Dashboard 1", dashboard.Title)
+	assert.Equal("global", dashboard.Source)
+}
+
+func TestDashboardSourcesPrecedence(t *testing.T) {
+	assert := assert.New(t)
+
+	service, k8s, _ := setupService()
+	files := new(kmock.ClientMock)
+	service.filesClient = files
+	service.config.DashboardSources = []string{SourceFiles, SourceNamespace, SourceGlobal}
+
+	fromFiles := fakeDashboard("1")
+	fromFiles.Spec.Title = "From files"
+	files.On("GetDashboard", "my-namespace", "dashboard1").Return(fromFiles, nil)
+	files.On("GetDashboard", "my-namespace", "dashboard2").Return(nil, errors.New("not found"))
+	k8s.On("GetDashboard", "my-namespace", "dashboard2").Return(nil, errors.New("not found"))
+	k8s.On("GetDashboard", "istio-system", "dashboard2").Return(fakeDashboard("2"), nil)
+
+	d, source, err := service.loadRawDashboardResource("my-namespace", "dashboard1")
+	assert.Nil(err)
+	assert.Equal("From files", d.Spec.Title)
+	assert.Equal(SourceFiles, source)
+	k8s.AssertNotCalled(t, "GetDashboard", "my-namespace", "dashboard1")
+
+	d, source, err = service.loadRawDashboardResource("my-namespace", "dashboard2")
+	assert.Nil(err)
+	assert.Equal("Dashboard 2", d.Spec.Title)
+	assert.Equal(SourceGlobal, source)
+
+	files.On("GetDashboards", "my-namespace").Return([]v1beta1.MonitoringDashboard{*fromFiles}, nil)
+	k8s.On("GetDashboards", "my-namespace").Return([]v1beta1.MonitoringDashboard{*fakeDashboard("1")}, nil)
+	k8s.On("GetDashboards", "istio-system").Return(nil, errors.New("denied"))
+
+	all, err := service.loadRawDashboardResources("my-namespace")
+	assert.Nil(err)
+	assert.Len(all, 1)
+	assert.Equal("From files", all["dashboard1"].Spec.Title)
+}
+
+func TestBuiltInDashboardFallback(t *testing.T) {
+	assert := assert.New(t)
+
+	service, k8s, _ := setupService()
+	k8s.On("GetDashboard", "my-namespace", "go").Return(nil, errors.New("not found"))
+	k8s.On("GetDashboard", "istio-system", "go").Return(nil, errors.New("not found"))
+
+	d, source, err := service.loadRawDashboardResource("my-namespace", "go")
+	assert.Nil(err)
+	assert.Equal("Go Metrics", d.Spec.Title)
+	assert.Equal(SourceBuiltIn, source)
+	k8s.AssertNumberOfCalls(t, "GetDashboard", 2)
+}
+
+func TestUnknownDashboardSource(t *testing.T) {
+	service, _, _ := setupService()
+	service.config.DashboardSources = []string{"foo"}
+
+	_, _, err := service.loadRawDashboardResource("my-namespace", "dashboard1")
+	assert.EqualError(t, err, "unknown dashboard source: foo")
 }
 
 func TestGetComposedDashboard(t *testing.T) {
 	assert := assert.New(t)
 
 	composed := fakeDashboard("2")
-	composed.Spec.Items = append(composed.Spec.Items, v1alpha1.MonitoringDashboardItem{Include: "dashboard1"})
+	composed.Spec.Items = append(composed.Spec.Items, v1beta1.MonitoringDashboardItem{Include: "dashboard1"})
 
 	// Setup mocks
 	service, k8s, _ := setupService()
 	k8s.On("GetDashboard", "my-namespace", "dashboard1").Return(fakeDashboard("1"), nil)
 	k8s.On("GetDashboard", "my-namespace", "dashboard2").Return(composed, nil)
 
-	d, err := service.loadAndResolveDashboardResource("my-namespace", "dashboard2", map[string]bool{})
+	d, _, err := service.loadAndResolveDashboardResource("my-namespace", "dashboard2", map[string]bool{})
 	assert.Nil(err)
 	k8s.AssertNumberOfCalls(t, "GetDashboard", 2)
 	assert.Equal("Dashboard 2", d.Spec.Title)
@@ -128,14 +191,14 @@ func TestGetComposedDashboardSingleChart(t *testing.T) {
 	assert := assert.New(t)
 
 	composed := fakeDashboard("2")
-	composed.Spec.Items = append(composed.Spec.Items, v1alpha1.MonitoringDashboardItem{Include: "dashboard1$My chart 1_2"})
+	composed.Spec.Items = append(composed.Spec.Items, v1beta1.MonitoringDashboardItem{Include: "dashboard1$My chart 1_2"})
 
 	// Setup mocks
 	service, k8s, _ := setupService()
 	k8s.On("GetDashboard", "my-namespace", "dashboard1").Return(fakeDashboard("1"), nil)
 	k8s.On("GetDashboard", "my-namespace", "dashboard2").Return(composed, nil)
 
-	d, err := service.loadAndResolveDashboardResource("my-namespace", "dashboard2", map[string]bool{})
+	d, _, err := service.loadAndResolveDashboardResource("my-namespace", "dashboard2", map[string]bool{})
 	assert.Nil(err)
 	k8s.AssertNumberOfCalls(t, "GetDashboard", 2)
 	assert.Equal("Dashboard 2", d.Spec.Title)
@@ -149,13 +212,13 @@ func TestCircularDependency(t *testing.T) {
 	assert := assert.New(t)
 
 	composed := fakeDashboard("2")
-	composed.Spec.Items = append(composed.Spec.Items, v1alpha1.MonitoringDashboardItem{Include: "dashboard2"})
+	composed.Spec.Items = append(composed.Spec.Items, v1beta1.MonitoringDashboardItem{Include: "dashboard2"})
 
 	// Setup mocks
 	service, k8s, _ := setupService()
 	k8s.On("GetDashboard", "my-namespace", "dashboard2").Return(composed, nil)
 
-	_, err := service.loadAndResolveDashboardResource("my-namespace", "dashboard2", map[string]bool{})
+	_, _, err := service.loadAndResolveDashboardResource("my-namespace", "dashboard2", map[string]bool{})
 	assert.Contains(err.Error(), "circular dependency detected")
 	k8s.AssertNumberOfCalls(t, "GetDashboard", 1)
 }
@@ -167,7 +230,7 @@ func TestDiscoveryMatcher(t *testing.T) {
 	d2 := fakeDashboard("2")
 	d3 := fakeDashboard("3")
 
-	dashboards := make(map[string]v1alpha1.MonitoringDashboard)
+	dashboards := make(map[string]v1beta1.MonitoringDashboard)
 	dashboards[d1.Name] = *d1
 	dashboards[d2.Name] = *d2
 	dashboards[d3.Name] = *d3
@@ -195,10 +258,10 @@ func TestDiscoveryMatcherWithComposition(t *testing.T) {
 
 	d1 := fakeDashboard("1")
 	d2 := fakeDashboard("2")
-	d2.Spec.Items = append(d2.Spec.Items, v1alpha1.MonitoringDashboardItem{Include: d1.Name})
+	d2.Spec.Items = append(d2.Spec.Items, v1beta1.MonitoringDashboardItem{Include: d1.Name})
 	d3 := fakeDashboard("3")
 
-	dashboards := make(map[string]v1alpha1.MonitoringDashboard)
+	dashboards := make(map[string]v1beta1.MonitoringDashboard)
 	dashboards[d1.Name] = *d1
 	dashboards[d2.Name] = *d2
 	dashboards[d3.Name] = *d3
@@ -219,23 +282,351 @@ func TestDiscoveryMatcherWithComposition(t *testing.T) {
 	assert.Equal("dashboard2", runtimes[0].DashboardRefs[0].Template)
 }
 
-func fakeDashboard(id string) *v1alpha1.MonitoringDashboard {
-	return &v1alpha1.MonitoringDashboard{
+func fakeDashboard(id string) *v1beta1.MonitoringDashboard {
+	return &v1beta1.MonitoringDashboard{
 		ObjectMeta: v1.ObjectMeta{
 			Name: "dashboard" + id,
 		},
-		Spec: v1alpha1.MonitoringDashboardSpec{
+		Spec: v1beta1.MonitoringDashboardSpec{
 			Title:      "Dashboard " + id,
 			Runtime:    "Runtime " + id,
 			DiscoverOn: "my_metric_" + id + "_1",
-			Items: []v1alpha1.MonitoringDashboardItem{
+			Items: []v1beta1.MonitoringDashboardItem{
 				{
-					Chart: kmock.FakeChart(id+"_1", "rate"),
+					Chart: kmock.FakeChart(id+"_1", v1beta1.Rate),
 				},
 				{
-					Chart: kmock.FakeChart(id+"_2", "histogram"),
+					Chart: kmock.FakeChart(id+"_2", v1beta1.Histogram),
 				},
 			},
 		},
 	}
 }
+
+func TestGetDashboardWithExpr(t *testing.T) {
+	assert := assert.New(t)
+
+	service, k8s, prom := setupService()
+	dashboard := fakeDashboard("1")
+	dashboard.Spec.Items = dashboard.Spec.Items[:1]
+	dashboard.Spec.Items[0].Chart.Query = v1beta1.MonitoringDashboardQuery{DataType: v1beta1.Expr, GroupLabels: []string{"code"}}
+	dashboard.Spec.Items[0].Chart.Metrics = []v1beta1.MonitoringDashboardMetric{{
+		DisplayName: "Error ratio",
+		Expr:        "sum(rate(errors$__labels[$__rate_interval]))$__by / sum(rate(total$__labels[$__rate_interval]))$__by",
+	}}
+	k8s.On("GetDashboard", "my-namespace", "dashboard1").Return(dashboard, nil)
+
+	query := model.DashboardQuery{Namespace: "my-namespace"}
+	query.FillDefaults()
+	metric := mock.FakeCounter(1)
+	metric.Query = `sum(rate(errors{namespace="my-namespace"}[1m])) by (code) / sum(rate(total{namespace="my-namespace"}[1m])) by (code)`
+	prom.On("FetchExprRange", dashboard.Spec.Items[0].Chart.Metrics[0].Expr, "{namespace=\"my-namespace\"}", "code", &query.MetricsQuery).Return(metric)
+
+	result, err := service.GetDashboard(query, "dashboard1")
+
+	assert.Nil(err)
+	assert.Len(result.Charts, 1)
+	assert.Len(result.Charts[0].Metrics, 1)
+	assert.Equal("Error ratio", result.Charts[0].Metrics[0].LabelSet["__name__"])
+	assert.Equal(float64(10), result.Charts[0].Metrics[0].Values[0].Value)
+	assert.Equal([]string{metric.Query}, result.Charts[0].Queries)
+}
+
+func TestGetDashboardCancelled(t *testing.T) {
+	assert := assert.New(t)
+
+	service, k8s, prom := setupService()
+	k8s.On("GetDashboard", "my-namespace", "dashboard1").Return(fakeDashboard("1"), nil)
+	query := model.DashboardQuery{Namespace: "my-namespace"}
+	query.FillDefaults()
+	prom.On("FetchRateRange", "my_metric_1_1", "{namespace=\"my-namespace\"}", "", &query.MetricsQuery).Return(prometheus.Metric{Err: context.Canceled})
+	prom.On("FetchHistogramRange", "my_metric_1_2", "{namespace=\"my-namespace\"}", "", &query.MetricsQuery).Return(prometheus.Histogram{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := service.GetDashboardWithContext(ctx, query, "dashboard1")
+
+	assert.Equal(context.Canceled, err)
+}
+
+func TestGetDashboardChartTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	service, k8s, prom := setupService()
+	service.config.ChartTimeout = time.Minute
+	dashboard := fakeDashboard("1")
+	dashboard.Spec.Items[1].Chart.Timeout = "50ms"
+	k8s.On("GetDashboard", "my-namespace", "dashboard1").Return(dashboard, nil)
+
+	query := model.DashboardQuery{Namespace: "my-namespace"}
+	query.FillDefaults()
+	expectedLabels := "{namespace=\"my-namespace\"}"
+	prom.On("FetchRateRange", "my_metric_1_1", expectedLabels, "", &query.MetricsQuery).Return(mock.FakeCounter(10))
+	prom.On("FetchHistogramRange", "my_metric_1_2", expectedLabels, "", &query.MetricsQuery).After(time.Second).Return(mock.FakeHistogram(11, 11))
+
+	start := time.Now()
+	result, err := service.GetDashboard(query, "dashboard1")
+
+	assert.Nil(err)
+	assert.True(time.Since(start) < time.Second)
+	assert.Len(result.Charts, 2)
+	assert.Empty(result.Charts[0].Error)
+	assert.Len(result.Charts[0].Metrics, 1)
+	assert.Equal("timeout: chart queries took longer than 50ms", result.Charts[1].Error)
+	assert.Empty(result.Charts[1].Metrics)
+}
+
+func TestGetDashboardTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	service, k8s, prom := setupService()
+	service.config.DashboardTimeout = 50 * time.Millisecond
+	k8s.On("GetDashboard", "my-namespace", "dashboard1").Return(fakeDashboard("1"), nil)
+
+	query := model.DashboardQuery{Namespace: "my-namespace"}
+	query.FillDefaults()
+	expectedLabels := "{namespace=\"my-namespace\"}"
+	prom.On("FetchRateRange", "my_metric_1_1", expectedLabels, "", &query.MetricsQuery).After(time.Second).Return(mock.FakeCounter(10))
+	prom.On("FetchHistogramRange", "my_metric_1_2", expectedLabels, "", &query.MetricsQuery).After(time.Second).Return(mock.FakeHistogram(11, 11))
+
+	result, err := service.GetDashboard(query, "dashboard1")
+
+	assert.Nil(err)
+	assert.Equal("Dashboard 1", result.Title)
+	assert.Equal("timeout: dashboard queries took longer than 50ms", result.Charts[0].Error)
+	assert.Equal("timeout: dashboard queries took longer than 50ms", result.Charts[1].Error)
+}
+
+func TestGetDashboardNativeHistogram(t *testing.T) {
+	assert := assert.New(t)
+
+	service, k8s, prom := setupService()
+	dashboard := fakeDashboard("1")
+	dashboard.Spec.Items = dashboard.Spec.Items[1:]
+	dashboard.Spec.Items[0].Chart.Query.HistogramMode = v1beta1.HistogramNative
+	k8s.On("GetDashboard", "my-namespace", "dashboard1").Return(dashboard, nil)
+
+	query := model.DashboardQuery{Namespace: "my-namespace"}
+	query.FillDefaults()
+	prom.On("FetchNativeHistogramRange", "my_metric_1_2", "{namespace=\"my-namespace\"}", "", &query.MetricsQuery).Return(mock.FakeHistogram(11, 12))
+
+	result, err := service.GetDashboard(query, "dashboard1")
+
+	assert.Nil(err)
+	assert.Len(result.Charts, 1)
+	assert.Len(result.Charts[0].Metrics, 2)
+	prom.AssertNotCalled(t, "FetchHistogramRange", "my_metric_1_2", "{namespace=\"my-namespace\"}", "", &query.MetricsQuery)
+}
+
+func TestGetDashboardHistogramAutoFallback(t *testing.T) {
+	assert := assert.New(t)
+
+	service, k8s, prom := setupService()
+	dashboard := fakeDashboard("1")
+	dashboard.Spec.Items = dashboard.Spec.Items[1:]
+	k8s.On("GetDashboard", "my-namespace", "dashboard1").Return(dashboard, nil)
+
+	query := model.DashboardQuery{Namespace: "my-namespace"}
+	query.FillDefaults()
+	expectedLabels := "{namespace=\"my-namespace\"}"
+	empty := prometheus.Histogram{"avg": prometheus.Metric{}, "0.99": prometheus.Metric{}}
+	prom.On("FetchHistogramRange", "my_metric_1_2", expectedLabels, "", &query.MetricsQuery).Return(empty)
+	prom.On("FetchNativeHistogramRange", "my_metric_1_2", expectedLabels, "", &query.MetricsQuery).Return(mock.FakeHistogram(11, 12))
+
+	result, err := service.GetDashboard(query, "dashboard1")
+
+	assert.Nil(err)
+	assert.Len(result.Charts, 1)
+	assert.Len(result.Charts[0].Metrics, 2)
+	prom.AssertExpectations(t)
+}
+
+func TestGetDashboardHeatmap(t *testing.T) {
+	assert := assert.New(t)
+
+	service, k8s, prom := setupService()
+	dashboard := fakeDashboard("1")
+	dashboard.Spec.Items = dashboard.Spec.Items[1:]
+	dashboard.Spec.Items[0].Chart.ChartType = v1beta1.ChartTypeHeatmap
+	k8s.On("GetDashboard", "my-namespace", "dashboard1").Return(dashboard, nil)
+
+	query := model.DashboardQuery{Namespace: "my-namespace"}
+	query.FillDefaults()
+	buckets := pmodel.Matrix{
+		{Metric: pmodel.Metric{"le": "1"}, Values: []pmodel.SamplePair{{Timestamp: 1000, Value: 2}}},
+		{Metric: pmodel.Metric{"le": "+Inf"}, Values: []pmodel.SamplePair{{Timestamp: 1000, Value: 5}}},
+	}
+	prom.On("FetchHistogramBuckets", "my_metric_1_2", "{namespace=\"my-namespace\"}", "", &query.MetricsQuery).Return(prometheus.Metric{Matrix: buckets})
+
+	result, err := service.GetDashboard(query, "dashboard1")
+
+	assert.Nil(err)
+	assert.Len(result.Charts, 1)
+	assert.Empty(result.Charts[0].Metrics)
+	assert.Len(result.Charts[0].Heatmaps, 1)
+	// unitScale applies to bucket bounds
+	assert.Equal([]string{"10", "+Inf"}, result.Charts[0].Heatmaps[0].Buckets)
+	assert.Equal([][]float64{{2}, {3}}, result.Charts[0].Heatmaps[0].Values)
+}
+
+func TestGetDashboardSummary(t *testing.T) {
+	assert := assert.New(t)
+
+	service, k8s, prom := setupService()
+	dashboard := fakeDashboard("1")
+	dashboard.Spec.Items = dashboard.Spec.Items[1:]
+	dashboard.Spec.Items[0].Chart.Query.DataType = v1beta1.Summary
+	k8s.On("GetDashboard", "my-namespace", "dashboard1").Return(dashboard, nil)
+
+	query := model.DashboardQuery{Namespace: "my-namespace"}
+	query.FillDefaults()
+	prom.On("FetchSummaryRange", "my_metric_1_2", "{namespace=\"my-namespace\"}", "", &query.MetricsQuery).Return(mock.FakeHistogram(11, 12))
+
+	result, err := service.GetDashboard(query, "dashboard1")
+
+	assert.Nil(err)
+	assert.Len(result.Charts, 1)
+	assert.Len(result.Charts[0].Metrics, 2)
+	assert.Equal("0.99", result.Charts[0].Metrics[0].LabelSet["__stat__"])
+	assert.Equal("avg", result.Charts[0].Metrics[1].LabelSet["__stat__"])
+}
+
+func TestGetDashboardRounding(t *testing.T) {
+	assert := assert.New(t)
+
+	service, k8s, prom := setupService()
+	dashboard := fakeDashboard("1")
+	dashboard.Spec.Items[0].Chart.Rounding = &v1beta1.MonitoringDashboardRounding{Precision: "0.1", ClientSide: true}
+	dashboard.Spec.Items[1].Chart.Rounding = &v1beta1.MonitoringDashboardRounding{Precision: "0.0001"}
+	k8s.On("GetDashboard", "my-namespace", "dashboard1").Return(dashboard, nil)
+
+	query := model.DashboardQuery{Namespace: "my-namespace"}
+	query.FillDefaults()
+	// Rounded on conversion: not in queries
+	none, precision := 0.0, 0.0001
+	rateQuery, histoQuery := query.MetricsQuery, query.MetricsQuery
+	rateQuery.Rounding = &none
+	histoQuery.Rounding = &precision
+	counter := prometheus.Metric{Matrix: pmodel.Matrix{{Metric: pmodel.Metric{}, Values: []pmodel.SamplePair{{Value: 1.234}}}}}
+	prom.On("FetchRateRange", "my_metric_1_1", "{namespace=\"my-namespace\"}", "", &rateQuery).Return(counter)
+	prom.On("FetchHistogramRange", "my_metric_1_2", "{namespace=\"my-namespace\"}", "", &histoQuery).Return(mock.FakeHistogram(11, 12))
+
+	result, err := service.GetDashboard(query, "dashboard1")
+
+	assert.Nil(err)
+	assert.Len(result.Charts, 2)
+	assert.Empty(result.Charts[0].Error)
+	// Note: fake dashboard has scale=10 for every chart; rounding is done before scaling
+	assert.InDelta(12.0, result.Charts[0].Metrics[0].Values[0].Value, 1e-9)
+	assert.Len(result.Charts[1].Metrics, 2)
+	prom.AssertExpectations(t)
+}
+
+func TestGetDashboardInstant(t *testing.T) {
+	assert := assert.New(t)
+
+	service, k8s, prom := setupService()
+	dashboard := fakeDashboard("1")
+	dashboard.Spec.Items = dashboard.Spec.Items[:1]
+	dashboard.Spec.Items[0].Chart.ChartType = v1beta1.ChartTypeStat
+	k8s.On("GetDashboard", "my-namespace", "dashboard1").Return(dashboard, nil)
+
+	query := model.DashboardQuery{Namespace: "my-namespace"}
+	query.FillDefaults()
+	instantQuery := query.MetricsQuery
+	instantQuery.Instant = true
+	vector := pmodel.Vector{{Metric: pmodel.Metric{}, Value: 5, Timestamp: pmodel.TimeFromUnixNano(query.End.UnixNano())}}
+	prom.On("FetchRateRange", "my_metric_1_1", "{namespace=\"my-namespace\"}", "", &instantQuery).Return(prometheus.Metric{Vector: vector})
+
+	result, err := service.GetDashboard(query, "dashboard1")
+
+	assert.Nil(err)
+	assert.Len(result.Charts, 1)
+	assert.Empty(result.Charts[0].Error)
+	assert.Empty(result.Charts[0].Metrics)
+	assert.Len(result.Charts[0].Vector, 1)
+	// Note: fake dashboard has scale=10 for every chart
+	assert.Equal(float64(50), result.Charts[0].Vector[0].Value.Value)
+	prom.AssertExpectations(t)
+}
+
+func TestLimitDataPoints(t *testing.T) {
+	assert := assert.New(t)
+
+	q := prometheus.MetricsQuery{}
+	q.FillDefaults()
+	q.End = time.Unix(1000000, 0)
+	q.Start = q.End.Add(-7 * 24 * time.Hour)
+	limitDataPoints(&q, 1000)
+	// 7 days in 999 steps: 605.4s, rounded up
+	assert.Equal(606*time.Second, q.Step)
+	assert.Equal("606s", q.RateInterval)
+	assert.Equal(int64(0), q.Start.Unix()%606)
+	assert.True(int(q.End.Sub(q.Start)/q.Step)+1 <= 1001)
+
+	// The requested step is kept when fine enough, as well as a larger rate interval
+	q.FillDefaults()
+	q.RateInterval = "5m"
+	start := q.Start
+	limitDataPoints(&q, 1000)
+	assert.Equal(15*time.Second, q.Step)
+	assert.Equal("5m", q.RateInterval)
+	assert.Equal(start, q.Start)
+}
+
+func TestGetDashboardMaxDataPoints(t *testing.T) {
+	assert := assert.New(t)
+
+	service, k8s, prom := setupService()
+	dashboard := fakeDashboard("1")
+	dashboard.Spec.Items[0].Chart.MaxDataPoints = 10
+	k8s.On("GetDashboard", "my-namespace", "dashboard1").Return(dashboard, nil)
+
+	query := model.DashboardQuery{Namespace: "my-namespace", MaxDataPoints: 100}
+	query.FillDefaults()
+	query.End = time.Unix(1800, 0)
+	query.Start = time.Unix(0, 0)
+	// The chart limit applies to the first chart, the dashboard query limit to the other one
+	chartQuery, dashboardQuery := query.MetricsQuery, query.MetricsQuery
+	chartQuery.Step, chartQuery.RateInterval = 200*time.Second, "200s"
+	dashboardQuery.Step = 19 * time.Second
+	prom.On("FetchRateRange", "my_metric_1_1", "{namespace=\"my-namespace\"}", "", &chartQuery).Return(mock.FakeCounter(10))
+	prom.On("FetchHistogramRange", "my_metric_1_2", "{namespace=\"my-namespace\"}", "", &dashboardQuery).Return(mock.FakeHistogram(11, 12))
+
+	result, err := service.GetDashboard(query, "dashboard1")
+
+	assert.Nil(err)
+	assert.Len(result.Charts, 2)
+	assert.Empty(result.Charts[0].Error)
+	assert.Empty(result.Charts[1].Error)
+	prom.AssertExpectations(t)
+}
+
+func TestGetDashboardLabelsFilters(t *testing.T) {
+	assert := assert.New(t)
+
+	service, k8s, prom := setupService()
+	dashboard := fakeDashboard("1")
+	dashboard.Spec.Items = dashboard.Spec.Items[:1]
+	k8s.On("GetDashboard", "my-namespace", "dashboard1").Return(dashboard, nil)
+
+	query := model.DashboardQuery{
+		Namespace: "my-namespace",
+		LabelsFilters: []prometheus.LabelMatcher{
+			{Name: "app", Type: prometheus.MatchNotEqual, Value: `x"} or vector(1) or up{a="`},
+			{Name: "version", Type: prometheus.MatchRegexp, Value: "v1|v2"},
+		},
+	}
+	query.FillDefaults()
+	expectedLabels := `{namespace="my-namespace",app!="x\"} or vector(1) or up{a=\"",version=~"v1|v2"}`
+	prom.On("FetchRateRange", "my_metric_1_1", expectedLabels, "", &query.MetricsQuery).Return(mock.FakeCounter(10))
+
+	result, err := service.GetDashboard(query, "dashboard1")
+
+	assert.Nil(err)
+	assert.Len(result.Charts[0].Metrics, 1)
+
+	query.LabelsFilters = []prometheus.LabelMatcher{{Name: "version", Type: prometheus.MatchRegexp, Value: "v1("}}
+	_, err = service.GetDashboard(query, "dashboard1")
+	assert.NotNil(err)
+}
@@ -10,7 +10,7 @@ import (
 
 	"github.com/kiali/k-charted/config/extconfig"
 	"github.com/kiali/k-charted/httputil"
-	"github.com/kiali/k-charted/kubernetes/v1alpha1"
+	"github.com/kiali/k-charted/kubernetes/v1beta1"
 	"github.com/kiali/k-charted/log"
 	"github.com/kiali/k-charted/model"
 )
@@ -18,7 +18,7 @@ import (
 type dashboardSupplier func(string, string, *extconfig.Auth) ([]byte, int, error)
 
 // GetGrafanaLinks returns the links to Grafana dashboards and other info, the HTTP status code (int) and eventually an error
-func GetGrafanaLinks(logger log.SafeAdapter, cfg extconfig.GrafanaConfig, linksSpec []v1alpha1.MonitoringDashboardExternalLink) ([]model.ExternalLink, int, error) {
+func GetGrafanaLinks(logger log.SafeAdapter, cfg extconfig.GrafanaConfig, linksSpec []v1beta1.MonitoringDashboardExternalLink) ([]model.ExternalLink, int, error) {
 	if cfg.URL == "" {
 		logger.Tracef("Skip checking Grafana links as Grafana is not configured")
 		return nil, 0, nil
@@ -26,7 +26,7 @@ func GetGrafanaLinks(logger log.SafeAdapter, cfg extconfig.GrafanaConfig, linksS
 	return getGrafanaLinks(logger, cfg, linksSpec, findDashboard)
 }
 
-func getGrafanaLinks(logger log.SafeAdapter, cfg extconfig.GrafanaConfig, linksSpec []v1alpha1.MonitoringDashboardExternalLink, dashboardSupplier dashboardSupplier) ([]model.ExternalLink, int, error) {
+func getGrafanaLinks(logger log.SafeAdapter, cfg extconfig.GrafanaConfig, linksSpec []v1beta1.MonitoringDashboardExternalLink, dashboardSupplier dashboardSupplier) ([]model.ExternalLink, int, error) {
 	apiURL := cfg.URL
 
 	// Find the in-cluster URL to reach Grafana's REST API if properties demand so
@@ -37,7 +37,7 @@ func getGrafanaLinks(logger log.SafeAdapter, cfg extconfig.GrafanaConfig, linksS
 	// Call Grafana REST API to get dashboard urls
 	linksOut := []model.ExternalLink{}
 	for _, linkSpec := range linksSpec {
-		if linkSpec.Type == "grafana" {
+		if linkSpec.Type == v1beta1.ExternalLinkGrafana {
 			dashboardPath, err := getDashboardPath(logger, apiURL, linkSpec.Name, &cfg.Auth, dashboardSupplier)
 			if err != nil {
 				return nil, http.StatusServiceUnavailable, err
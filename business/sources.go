@@ -0,0 +1,96 @@
+package business
+
+import (
+	"fmt"
+
+	"github.com/kiali/k-charted/kubernetes"
+)
+
+// Dashboard sources, to be listed in config.Config.DashboardSources by order of precedence
+const (
+	// SourceNamespace stands for MonitoringDashboard resources in the requested namespace
+	SourceNamespace = "namespace"
+	// SourceGlobal stands for MonitoringDashboard resources in config.GlobalNamespace
+	SourceGlobal = "global"
+	// SourceFiles stands for dashboard files in the requested namespace directory (see config.DashboardFiles)
+	SourceFiles = "files"
+	// SourceFilesGlobal stands for dashboard files in the global directory (see config.DashboardFiles)
+	SourceFilesGlobal = "files-global"
+	// SourceBuiltIn stands for the dashboards library compiled into k-charted
+	SourceBuiltIn = "builtin"
+)
+
+// dashboardLayer is one level of the dashboards override mechanism
+type dashboardLayer struct {
+	name string
+	// namespace is the fixed namespace to look into; when empty, the requested namespace is used
+	namespace string
+	// backend identifies the underlying client, so that the same backend & namespace aren't queried twice
+	backend string
+	client  func() (kubernetes.ClientInterface, error)
+}
+
+func (l *dashboardLayer) resolveNamespace(namespace string) string {
+	if l.namespace != "" {
+		return l.namespace
+	}
+	return namespace
+}
+
+// defaultSources keeps the historical override mechanism: dashboards in the requested namespace first, then in the global namespace.
+// Built-in dashboards come last.
+func defaultSources(filesEnabled bool) []string {
+	if filesEnabled {
+		return []string{SourceFiles, SourceFilesGlobal, SourceBuiltIn}
+	}
+	return []string{SourceNamespace, SourceGlobal, SourceBuiltIn}
+}
+
+// layers returns the chain of dashboard sources, by order of precedence
+func (in *DashboardsService) layers() ([]dashboardLayer, error) {
+	sources := in.config.DashboardSources
+	if len(sources) == 0 {
+		sources = defaultSources(in.config.DashboardFiles.Path != "")
+	}
+	layers := []dashboardLayer{}
+	for _, source := range sources {
+		switch source {
+		case SourceNamespace:
+			layers = append(layers, dashboardLayer{name: source, backend: "kubernetes", client: in.k8s})
+		case SourceGlobal:
+			if in.config.GlobalNamespace != "" {
+				layers = append(layers, dashboardLayer{name: source, namespace: in.config.GlobalNamespace, backend: "kubernetes", client: in.k8s})
+			}
+		case SourceFiles:
+			layers = append(layers, dashboardLayer{name: source, backend: "files", client: in.files})
+		case SourceFilesGlobal:
+			if in.config.GlobalNamespace != "" {
+				layers = append(layers, dashboardLayer{name: source, namespace: in.config.GlobalNamespace, backend: "files", client: in.files})
+			}
+		case SourceBuiltIn:
+			layers = append(layers, dashboardLayer{name: source, backend: "builtin", client: builtIn})
+		default:
+			return nil, fmt.Errorf("unknown dashboard source: %s", source)
+		}
+	}
+	return layers, nil
+}
+
+func (in *DashboardsService) files() (kubernetes.ClientInterface, error) {
+	// Lazy init
+	if in.filesClient == nil {
+		if in.config.DashboardFiles.Path == "" {
+			return nil, fmt.Errorf("dashboard files source requires a path to be configured")
+		}
+		client, err := kubernetes.GetFileClient(in.config.DashboardFiles, in.config.GlobalNamespace)
+		if err != nil {
+			return nil, fmt.Errorf("cannot initialize dashboard files Client: %v", err)
+		}
+		in.filesClient = client
+	}
+	return in.filesClient, nil
+}
+
+func builtIn() (kubernetes.ClientInterface, error) {
+	return kubernetes.GetBuiltInClient()
+}
@@ -0,0 +1,18 @@
+package business
+
+import (
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/kiali/k-charted/kubernetes/v1beta1"
+	"github.com/kiali/k-charted/kubernetes/v1beta1/validation"
+)
+
+// ValidateDashboard checks a JSON-encoded MonitoringDashboard, of any supported version, that is about to be created or updated in the given namespace.
+// Included dashboards are looked up from the configured dashboard sources.
+func (in *DashboardsService) ValidateDashboard(namespace string, raw []byte) (field.ErrorList, error) {
+	_, errs, err := validation.ValidateRawDashboard(raw, func(name string) (*v1beta1.MonitoringDashboard, error) {
+		dashboard, _, err := in.loadRawDashboardResource(namespace, name)
+		return dashboard, err
+	})
+	return errs, err
+}
@@ -0,0 +1,140 @@
+package business
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/kiali/k-charted/kubernetes/v1beta1"
+	"github.com/kiali/k-charted/model"
+	"github.com/kiali/k-charted/prometheus"
+)
+
+// variableRef matches variable references such as $name or ${name}
+var variableRef = regexp.MustCompile(`\$\{(\w+)\}|\$(\w+)`)
+
+// queryValue matches values which are safe to substitute in metric names and expressions. Options of label_values variables
+// come from Prometheus, hence from workloads: a value such as `x"} or vector(1) or y{a="` must not make its way into queries.
+var queryValue = regexp.MustCompile(`^[a-zA-Z0-9_:.-]*$`)
+
+// substituteVariables replaces variable references with their values. References to unknown variables are left untouched.
+func substituteVariables(s string, values map[string]string) string {
+	if !strings.Contains(s, "$") {
+		return s
+	}
+	return variableRef.ReplaceAllStringFunc(s, func(ref string) string {
+		if value, ok := values[strings.Trim(ref, "${}")]; ok {
+			return value
+		}
+		return ref
+	})
+}
+
+// substituteLabelsFilters replaces variable references in labels filters values.
+// Like in substituteVariables, anything that isn't a reference to a dashboard variable, such as "price$USD", is kept literally.
+func substituteLabelsFilters(labelsFilters []prometheus.LabelMatcher, values map[string]string) []prometheus.LabelMatcher {
+	substituted := make([]prometheus.LabelMatcher, 0, len(labelsFilters))
+	for _, m := range labelsFilters {
+		m.Value = substituteVariables(m.Value, values)
+		substituted = append(substituted, m)
+	}
+	return substituted
+}
+
+// resolveVariables computes the options and the current value of each dashboard variable, in declaration order.
+// It returns the resolved variables for the UI and their values by name.
+// Requested values are only accepted when they belong to the options, so that arbitrary input doesn't make its way into queries.
+func (in *DashboardsService) resolveVariables(ctx context.Context, variables []v1beta1.MonitoringDashboardVariable, params model.DashboardQuery, targets []clusterTarget) ([]model.Variable, map[string]string) {
+	resolved := []model.Variable{}
+	values := make(map[string]string)
+	for _, variable := range variables {
+		options := []string{}
+		switch variable.Type {
+		case v1beta1.VariableConstant:
+			options = append(options, substituteVariables(variable.Value, values))
+		case v1beta1.VariableCustom:
+			for _, option := range variable.Options {
+				options = append(options, substituteVariables(option, values))
+			}
+		case v1beta1.VariableLabelValues:
+			options = in.fetchLabelValues(ctx, variable, params, values, targets)
+		}
+
+		value := ""
+		if requested, ok := params.Variables[variable.Name]; ok && variable.Type != v1beta1.VariableConstant && containsString(options, requested) {
+			value = requested
+		} else if variable.Default != "" && containsString(options, variable.Default) {
+			value = variable.Default
+		} else if len(options) > 0 {
+			value = options[0]
+		}
+		values[variable.Name] = value
+
+		displayName := variable.DisplayName
+		if displayName == "" {
+			displayName = variable.Name
+		}
+		resolved = append(resolved, model.Variable{
+			Name:        variable.Name,
+			DisplayName: displayName,
+			Options:     options,
+			Value:       value,
+		})
+	}
+	return resolved, values
+}
+
+// fetchLabelValues returns the options of a label_values variable, looked up in the requested namespace and labels filters.
+// In all-clusters mode, options are merged from every cluster.
+func (in *DashboardsService) fetchLabelValues(ctx context.Context, variable v1beta1.MonitoringDashboardVariable, params model.DashboardQuery, values map[string]string, targets []clusterTarget) []string {
+	series := substituteVariables(variable.Metric, values) + in.buildLabels(params.Namespace, substituteLabelsFilters(params.LabelsFilters, values))
+	options := [][]string{}
+	for _, target := range targets {
+		opts, err := target.prom.GetLabelValues(ctx, variable.Label, []string{series})
+		if err != nil {
+			in.Logger.Warningf("cannot resolve variable %s, label values not found for %s: %v", variable.Name, series, err)
+			continue
+		}
+		options = append(options, opts)
+	}
+	if len(options) == 0 {
+		return []string{}
+	}
+	return mergeOptions(options)
+}
+
+// applyVariables substitutes variables in titles, metric names and expressions of a resolved dashboard.
+// Values outside of queryValue are only substituted in titles and names: references to them are left untouched in
+// metric names and expressions, which then fail to be checked and result in a chart error.
+func applyVariables(dashboard *v1beta1.MonitoringDashboard, values map[string]string) {
+	if len(values) == 0 {
+		return
+	}
+	safeValues := make(map[string]string, len(values))
+	for name, value := range values {
+		if queryValue.MatchString(value) {
+			safeValues[name] = value
+		}
+	}
+	dashboard.Spec.Title = substituteVariables(dashboard.Spec.Title, values)
+	for _, item := range dashboard.Spec.Items {
+		if item.Chart == nil {
+			continue
+		}
+		item.Chart.Name = substituteVariables(item.Chart.Name, values)
+		for i := range item.Chart.Metrics {
+			item.Chart.Metrics[i].MetricName = substituteVariables(item.Chart.Metrics[i].MetricName, safeValues)
+			item.Chart.Metrics[i].DisplayName = substituteVariables(item.Chart.Metrics[i].DisplayName, values)
+			item.Chart.Metrics[i].Expr = substituteVariables(item.Chart.Metrics[i].Expr, safeValues)
+		}
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package business
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiali/k-charted/kubernetes/v1beta1"
+	"github.com/kiali/k-charted/model"
+	"github.com/kiali/k-charted/prometheus"
+	"github.com/kiali/k-charted/prometheus/mock"
+)
+
+func TestSubstituteVariables(t *testing.T) {
+	assert := assert.New(t)
+
+	values := map[string]string{"a": "foo", "ab": "bar"}
+	assert.Equal("foo bar foo_x bar", substituteVariables("$a $ab ${a}_x ${ab}", values))
+	assert.Equal("$unknown ${unknown}", substituteVariables("$unknown ${unknown}", values))
+	assert.Equal("no variable", substituteVariables("no variable", values))
+
+	filters := substituteLabelsFilters([]prometheus.LabelMatcher{
+		{Name: "app", Type: prometheus.MatchRegexp, Value: "$a|bar"},
+		{Name: "version", Type: prometheus.MatchEqual, Value: "$unknown"},
+		{Name: "other", Type: prometheus.MatchNotEqual, Value: "v1"},
+		{Name: "price", Type: prometheus.MatchEqual, Value: "price$USD"},
+	}, values)
+	assert.Equal([]prometheus.LabelMatcher{
+		{Name: "app", Type: prometheus.MatchRegexp, Value: "foo|bar"},
+		{Name: "version", Type: prometheus.MatchEqual, Value: "$unknown"},
+		{Name: "other", Type: prometheus.MatchNotEqual, Value: "v1"},
+		{Name: "price", Type: prometheus.MatchEqual, Value: "price$USD"},
+	}, filters)
+}
+
+func TestResolveVariables(t *testing.T) {
+	assert := assert.New(t)
+
+	service, _, prom := setupService()
+	prom.On("GetLabelValues", "instance", []string{"up_prefix_up{namespace=\"my-namespace\"}"}).Return([]string{"i1", "i2"}, nil)
+	prom.On("GetLabelValues", "job", []string{"{namespace=\"my-namespace\"}"}).Return([]string(nil), errors.New("unavailable"))
+
+	variables := []v1beta1.MonitoringDashboardVariable{
+		{Name: "prefix", Type: v1beta1.VariableConstant, Value: "up_prefix"},
+		{Name: "env", DisplayName: "Environment", Type: v1beta1.VariableCustom, Options: []string{"dev", "prod"}, Default: "prod"},
+		{Name: "instance", Type: v1beta1.VariableLabelValues, Label: "instance", Metric: "${prefix}_up"},
+		{Name: "job", Type: v1beta1.VariableLabelValues, Label: "job"},
+	}
+	params := model.DashboardQuery{
+		Namespace: "my-namespace",
+		Variables: map[string]string{"prefix": "forbidden", "instance": "i2", "env": "not-an-option"},
+	}
+	resolved, values := service.resolveVariables(context.Background(), variables, params, []clusterTarget{{prom: prom}})
+
+	assert.Equal(map[string]string{"prefix": "up_prefix", "env": "prod", "instance": "i2", "job": ""}, values)
+	assert.Len(resolved, 4)
+	assert.Equal(model.Variable{Name: "env", DisplayName: "Environment", Options: []string{"dev", "prod"}, Value: "prod"}, resolved[1])
+	assert.Equal(model.Variable{Name: "instance", DisplayName: "instance", Options: []string{"i1", "i2"}, Value: "i2"}, resolved[2])
+	assert.Empty(resolved[3].Options)
+}
+
+func TestApplyVariablesUnsafeValues(t *testing.T) {
+	assert := assert.New(t)
+
+	dashboard := fakeDashboard("1")
+	dashboard.Spec.Items[0].Chart.Name = "Chart $instance"
+	dashboard.Spec.Items[0].Chart.Metrics[0].MetricName = "my_metric_$instance"
+	dashboard.Spec.Items[1].Chart.Metrics[0].Expr = `sum(rate(foo{instance="$instance",job="$job"}[5m]))`
+	applyVariables(dashboard, map[string]string{"instance": `x"} or vector(1) or y{a="`, "job": "api-server:8080"})
+
+	assert.Equal(`Chart x"} or vector(1) or y{a="`, dashboard.Spec.Items[0].Chart.Name)
+	assert.Equal("my_metric_$instance", dashboard.Spec.Items[0].Chart.Metrics[0].MetricName)
+	assert.Equal(`sum(rate(foo{instance="$instance",job="api-server:8080"}[5m]))`, dashboard.Spec.Items[1].Chart.Metrics[0].Expr)
+}
+
+func TestGetDashboardWithVariables(t *testing.T) {
+	assert := assert.New(t)
+
+	service, k8s, prom := setupService()
+	dashboard := fakeDashboard("1")
+	dashboard.Spec.Title = "Dashboard for $suffix"
+	dashboard.Spec.Items[0].Chart.Name = "Chart $suffix"
+	dashboard.Spec.Items[0].Chart.Metrics[0].MetricName = "my_metric_${suffix}"
+	dashboard.Spec.Variables = []v1beta1.MonitoringDashboardVariable{
+		{Name: "suffix", Type: v1beta1.VariableCustom, Options: []string{"a", "b"}},
+	}
+	k8s.On("GetDashboard", "my-namespace", "dashboard1").Return(dashboard, nil)
+
+	query := model.DashboardQuery{
+		Namespace:     "my-namespace",
+		LabelsFilters: []prometheus.LabelMatcher{{Name: "app", Type: prometheus.MatchEqual, Value: "app_$suffix"}},
+		Variables:     map[string]string{"suffix": "b"},
+	}
+	query.FillDefaults()
+	expectedLabels := "{namespace=\"my-namespace\",app=\"app_b\"}"
+	prom.On("FetchRateRange", "my_metric_b", expectedLabels, "", &query.MetricsQuery).Return(mock.FakeCounter(10))
+	prom.On("FetchHistogramRange", "my_metric_1_2", expectedLabels, "", &query.MetricsQuery).Return(mock.FakeHistogram(11, 11))
+
+	result, err := service.GetDashboard(query, "dashboard1")
+
+	assert.Nil(err)
+	assert.Equal("Dashboard for b", result.Title)
+	assert.Equal("Chart b", result.Charts[0].Name)
+	assert.Equal([]model.Variable{{Name: "suffix", DisplayName: "suffix", Options: []string{"a", "b"}, Value: "b"}}, result.Variables)
+	prom.AssertExpectations(t)
+}
@@ -1,14 +1,28 @@
 package config
 
 import (
+	"time"
+
 	"github.com/kiali/k-charted/config/extconfig"
 	"github.com/kiali/k-charted/model"
 )
 
 type Config struct {
-	Prometheus      extconfig.PrometheusConfig `yaml:"prometheus"`
-	Grafana         extconfig.GrafanaConfig    `yaml:"grafana"`
-	GlobalNamespace string                     `yaml:"global_namespace"`
-	NamespaceLabel  string                     `yaml:"namespace_label"`
-	PodsLoader      func(string, string) ([]model.Pod, error)
+	Prometheus     extconfig.PrometheusConfig     `yaml:"prometheus"`
+	Grafana        extconfig.GrafanaConfig        `yaml:"grafana"`
+	Kubernetes     extconfig.KubernetesConfig     `yaml:"kubernetes"`
+	DashboardFiles extconfig.DashboardFilesConfig `yaml:"dashboard_files"`
+	// DashboardSources lists where dashboards are looked for, by order of precedence (see business.Source* constants)
+	DashboardSources []string `yaml:"dashboard_sources"`
+	// Clusters are the clusters that can be queried in addition to the default one, defined by Prometheus and Kubernetes
+	Clusters []extconfig.ClusterConfig `yaml:"clusters"`
+	// ClusterName is the name of the default cluster, which tags its series when querying all clusters ("default" when empty)
+	ClusterName string `yaml:"cluster_name"`
+	// ChartTimeout is the default time budget of the queries of a chart, which can be overridden per chart. No timeout when zero.
+	ChartTimeout time.Duration `yaml:"chart_timeout"`
+	// DashboardTimeout is the time budget of the queries of a whole dashboard. No timeout when zero.
+	DashboardTimeout time.Duration `yaml:"dashboard_timeout"`
+	GlobalNamespace  string        `yaml:"global_namespace"`
+	NamespaceLabel   string        `yaml:"namespace_label"`
+	PodsLoader       func(string, string) ([]model.Pod, error)
 }
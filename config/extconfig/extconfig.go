@@ -1,5 +1,7 @@
 package extconfig
 
+import "time"
+
 // The valid auth strategies and values for cookie handling
 const (
 	// These constants are used for external services auth (Prometheus, Grafana ...) ; not for Kiali auth
@@ -8,10 +10,26 @@ const (
 	AuthTypeNone   = "none"
 )
 
-// PrometheusConfig describes configuration of the Prometheus component
+// PrometheusConfig describes configuration of the Prometheus component.
+// Multi-tenant backends such as Cortex, Mimir or Thanos are supported through the tenant settings: each request is sent
+// with the tenant mapped to the queried namespace in NamespaceTenants, or with Tenant by default, in the TenantHeader header
+// (X-Scope-OrgID when empty). PartialResponse and Dedup set the Thanos query parameters of the same names, when not nil.
+// MaxConcurrentQueries caps the number of queries run concurrently against the server, across all requests (20 when zero).
+// When CacheTTL is set, range query results are cached for that duration, up to CacheMaxEntries results (1000 when zero).
+// When CacheSplitInterval is also set, range queries are split into chunks of that interval: historical chunks are cached
+// while the most recent ones are queried again, so that refreshing a dashboard only queries the newest samples.
 type PrometheusConfig struct {
-	URL  string `yaml:"url"`
-	Auth Auth   `yaml:"auth"`
+	URL                  string            `yaml:"url"`
+	Auth                 Auth              `yaml:"auth"`
+	MaxConcurrentQueries int               `yaml:"max_concurrent_queries"`
+	Tenant               string            `yaml:"tenant"`
+	TenantHeader         string            `yaml:"tenant_header"`
+	NamespaceTenants     map[string]string `yaml:"namespace_tenants"`
+	PartialResponse      *bool             `yaml:"partial_response"`
+	Dedup                *bool             `yaml:"dedup"`
+	CacheTTL             time.Duration     `yaml:"cache_ttl"`
+	CacheMaxEntries      int               `yaml:"cache_max_entries"`
+	CacheSplitInterval   time.Duration     `yaml:"cache_split_interval"`
 }
 
 // GrafanaConfig describes configuration of the Grafana component
@@ -21,6 +39,35 @@ type GrafanaConfig struct {
 	Auth         Auth   `yaml:"auth"`
 }
 
+// KubernetesConfig describes how to reach the Kubernetes API server.
+// When running in a pod with no explicit setting, the in-cluster configuration is used; otherwise the standard
+// kubeconfig loading rules apply (KUBECONFIG env var, then ~/.kube/config), unless ConfigPath is set.
+// When CacheEnabled is set, MonitoringDashboards are watched and served from memory; CacheResyncPeriod
+// sets the informer resync period (zero disables periodic resync).
+type KubernetesConfig struct {
+	ConfigPath        string        `yaml:"config_path"`
+	Context           string        `yaml:"context"`
+	CacheEnabled      bool          `yaml:"cache_enabled"`
+	CacheResyncPeriod time.Duration `yaml:"cache_resync_period"`
+}
+
+// DashboardFilesConfig describes how to load MonitoringDashboards from files, as an alternative to Kubernetes resources.
+// Path is the root directory, containing one sub-directory per namespace. Dashboards in GlobalDir sub-directory are served
+// for the global namespace. Files are polled every ReloadInterval (10s when zero, disabled when negative).
+type DashboardFilesConfig struct {
+	Path           string        `yaml:"path"`
+	GlobalDir      string        `yaml:"global_dir"`
+	ReloadInterval time.Duration `yaml:"reload_interval"`
+}
+
+// ClusterConfig describes a cluster of a multi-cluster setup, with its own Prometheus and Kubernetes API server.
+// Name identifies the cluster in queries and is used as the cluster label of series fetched in all-clusters mode.
+type ClusterConfig struct {
+	Name       string           `yaml:"name"`
+	Prometheus PrometheusConfig `yaml:"prometheus"`
+	Kubernetes KubernetesConfig `yaml:"kubernetes"`
+}
+
 // Auth provides authentication data for external services
 type Auth struct {
 	Type               string `yaml:"type"`
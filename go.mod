@@ -4,6 +4,8 @@ go 1.14
 
 require (
 	github.com/google/gofuzz v1.1.0 // indirect
+	github.com/hashicorp/golang-lru v0.5.1 // indirect
+	github.com/imdario/mergo v0.3.5 // indirect
 	github.com/prometheus/client_golang v0.9.4
 	github.com/prometheus/common v0.4.1
 	github.com/spf13/pflag v1.0.5 // indirect
@@ -12,10 +14,10 @@ require (
 	golang.org/x/text v0.3.3 // indirect
 	golang.org/x/time v0.0.0-20200630173020-3af7569d3a1e // indirect
 	gopkg.in/inf.v0 v0.9.1 // indirect
-	k8s.io/api v0.0.0-20190313235455-40a48860b5ab // indirect
+	k8s.io/api v0.0.0-20190313235455-40a48860b5ab
 	k8s.io/apimachinery v0.0.0-20190816221834-a9f1d8a9c101
 	k8s.io/client-go v11.0.1-0.20190820062731-7e43eff7c80a+incompatible
 	k8s.io/klog v1.0.0 // indirect
 	k8s.io/utils v0.0.0-20200720150651-0bdb4ca86cbc // indirect
-	sigs.k8s.io/yaml v1.2.0 // indirect
+	sigs.k8s.io/yaml v1.2.0
 )
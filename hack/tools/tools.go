@@ -0,0 +1,11 @@
+//go:build tools
+// +build tools
+
+// Package tools pins the versions of the tools used to build K-Charted. It's a separate module, so that tools
+// dependencies don't conflict with the ones of K-Charted.
+package tools
+
+import (
+	// controller-gen generates the CRD manifest, see "make gencrd"
+	_ "sigs.k8s.io/controller-tools/cmd/controller-gen"
+)
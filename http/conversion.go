@@ -0,0 +1,91 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/kiali/k-charted/kubernetes/v1alpha1"
+	"github.com/kiali/k-charted/kubernetes/v1beta1"
+	"github.com/kiali/k-charted/log"
+)
+
+// conversionReview mirrors the ConversionReview of apiextensions.k8s.io (v1 and v1beta1 share the same structure)
+type conversionReview struct {
+	meta_v1.TypeMeta `json:",inline"`
+	Request          *conversionRequest  `json:"request,omitempty"`
+	Response         *conversionResponse `json:"response,omitempty"`
+}
+
+type conversionRequest struct {
+	UID               types.UID              `json:"uid"`
+	DesiredAPIVersion string                 `json:"desiredAPIVersion"`
+	Objects           []runtime.RawExtension `json:"objects"`
+}
+
+type conversionResponse struct {
+	UID              types.UID              `json:"uid"`
+	ConvertedObjects []runtime.RawExtension `json:"convertedObjects"`
+	Result           meta_v1.Status         `json:"result"`
+}
+
+// ConversionWebhookHandler is the handler for the Kubernetes conversion webhook of MonitoringDashboard resources,
+// allowing v1alpha1 and v1beta1 objects to be served in either version.
+// It expects a ConversionReview as request body, and responds with the same ConversionReview filled with the converted objects.
+func ConversionWebhookHandler(body io.Reader, w http.ResponseWriter, logger log.LogAdapter) {
+	safeLogger := log.NewSafeAdapter(logger)
+
+	raw, err := ioutil.ReadAll(body)
+	if err != nil {
+		respondWithError(safeLogger, w, http.StatusBadRequest, err.Error())
+		return
+	}
+	review := conversionReview{}
+	if err = json.Unmarshal(raw, &review); err != nil || review.Request == nil {
+		respondWithError(safeLogger, w, http.StatusBadRequest, "bad request, ConversionReview expected")
+		return
+	}
+
+	request := review.Request
+	response := conversionResponse{
+		UID:              request.UID,
+		ConvertedObjects: []runtime.RawExtension{},
+		Result:           meta_v1.Status{Status: meta_v1.StatusSuccess},
+	}
+	for _, object := range request.Objects {
+		converted, err := convertDashboard(object.Raw, request.DesiredAPIVersion)
+		if err != nil {
+			safeLogger.Errorf("cannot convert MonitoringDashboard to %s: %v", request.DesiredAPIVersion, err)
+			response.ConvertedObjects = []runtime.RawExtension{}
+			response.Result = meta_v1.Status{Status: meta_v1.StatusFailure, Message: err.Error()}
+			break
+		}
+		response.ConvertedObjects = append(response.ConvertedObjects, runtime.RawExtension{Raw: converted})
+	}
+
+	respondWithJSON(safeLogger, w, http.StatusOK, conversionReview{
+		TypeMeta: review.TypeMeta,
+		Response: &response,
+	})
+}
+
+// convertDashboard converts a JSON MonitoringDashboard of any supported version into the desired version
+func convertDashboard(raw []byte, desiredAPIVersion string) ([]byte, error) {
+	dashboard, err := v1beta1.Decode(raw)
+	if err != nil {
+		return nil, err
+	}
+	switch desiredAPIVersion {
+	case v1beta1.GroupVersion.String():
+		return json.Marshal(dashboard)
+	case v1alpha1.GroupVersion.String():
+		return json.Marshal(v1beta1.ConvertToV1alpha1(dashboard))
+	}
+	return nil, fmt.Errorf("unsupported apiVersion %s", desiredAPIVersion)
+}
@@ -0,0 +1,81 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiali/k-charted/kubernetes/v1alpha1"
+	"github.com/kiali/k-charted/kubernetes/v1beta1"
+	"github.com/kiali/k-charted/log"
+)
+
+func runConversion(t *testing.T, desiredAPIVersion string, objects ...string) conversionReview {
+	body := `{"apiVersion":"apiextensions.k8s.io/v1","kind":"ConversionReview","request":{"uid":"123","desiredAPIVersion":"` + desiredAPIVersion + `","objects":[` + strings.Join(objects, ",") + `]}}`
+	rr := httptest.NewRecorder()
+	ConversionWebhookHandler(strings.NewReader(body), rr, log.LogAdapter{})
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var review conversionReview
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &review))
+	assert.Equal(t, "apiextensions.k8s.io/v1", review.APIVersion)
+	assert.Equal(t, "123", string(review.Response.UID))
+	return review
+}
+
+func TestConvertToV1beta1(t *testing.T) {
+	assert := assert.New(t)
+
+	review := runConversion(t, "monitoring.kiali.io/v1beta1",
+		`{"apiVersion":"monitoring.kiali.io/v1alpha1","kind":"MonitoringDashboard","metadata":{"name":"d","namespace":"ns","uid":"abc"},"spec":{"title":"D","items":[
+			{"chart":{"name":"c1","dataType":"raw","aggregator":"sum","metricName":"m"}}
+		]}}`)
+	assert.Equal("Success", review.Response.Result.Status)
+	assert.Len(review.Response.ConvertedObjects, 1)
+
+	var d v1beta1.MonitoringDashboard
+	assert.Nil(json.Unmarshal(review.Response.ConvertedObjects[0].Raw, &d))
+	assert.Equal("monitoring.kiali.io/v1beta1", d.APIVersion)
+	assert.Equal("abc", string(d.UID))
+	assert.Equal(v1beta1.Raw, d.Spec.Items[0].Chart.Query.DataType)
+	assert.Equal(v1beta1.AggregatorSum, d.Spec.Items[0].Chart.Query.Aggregator)
+	assert.Equal("m", d.Spec.Items[0].Chart.Metrics[0].MetricName)
+}
+
+func TestConvertToV1alpha1(t *testing.T) {
+	assert := assert.New(t)
+
+	review := runConversion(t, "monitoring.kiali.io/v1alpha1",
+		`{"apiVersion":"monitoring.kiali.io/v1beta1","kind":"MonitoringDashboard","metadata":{"name":"d"},"spec":{"title":"D","items":[
+			{"chart":{"name":"c1","query":{"dataType":"rate"},"metrics":[{"metricName":"m"}],"sort":{"label":"code","parseAs":"int"}}}
+		]}}`,
+		`{"apiVersion":"monitoring.kiali.io/v1alpha1","kind":"MonitoringDashboard","metadata":{"name":"d2"},"spec":{"title":"D2"}}`)
+	assert.Equal("Success", review.Response.Result.Status)
+	assert.Len(review.Response.ConvertedObjects, 2)
+
+	var d v1alpha1.MonitoringDashboard
+	assert.Nil(json.Unmarshal(review.Response.ConvertedObjects[0].Raw, &d))
+	assert.Equal("monitoring.kiali.io/v1alpha1", d.APIVersion)
+	assert.Equal(v1alpha1.Rate, d.Spec.Items[0].Chart.DataType)
+	assert.Equal("code", d.Spec.Items[0].Chart.SortLabel)
+	assert.Equal("int", d.Spec.Items[0].Chart.SortLabelParseAs)
+}
+
+func TestConvertToUnknownVersion(t *testing.T) {
+	assert := assert.New(t)
+
+	review := runConversion(t, "monitoring.kiali.io/v2",
+		`{"apiVersion":"monitoring.kiali.io/v1alpha1","kind":"MonitoringDashboard","metadata":{"name":"d"},"spec":{"title":"D"}}`)
+	assert.Equal("Failure", review.Response.Result.Status)
+	assert.Empty(review.Response.ConvertedObjects)
+}
+
+func TestConversionBadRequest(t *testing.T) {
+	rr := httptest.NewRecorder()
+	ConversionWebhookHandler(strings.NewReader("{}"), rr, log.LogAdapter{})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -1,10 +1,10 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/url"
-	"strings"
 
 	"k8s.io/apimachinery/pkg/api/errors"
 
@@ -14,10 +14,15 @@ import (
 	"github.com/kiali/k-charted/model"
 )
 
-// DashboardHandler is the API handler to fetch runtime metrics to be displayed.
-// It expects "namespace" and "dashboard" to be provided as path params. Label filters can be provided as query params
-// (see also: ExtractDashboardQueryParams)
+// DashboardHandler is the API handler to fetch runtime metrics to be displayed, see DashboardHandlerWithContext
 func DashboardHandler(queryParams url.Values, pathParams map[string]string, w http.ResponseWriter, conf config.Config, logger log.LogAdapter) {
+	DashboardHandlerWithContext(context.Background(), queryParams, pathParams, w, conf, logger)
+}
+
+// DashboardHandlerWithContext is the API handler to fetch runtime metrics to be displayed.
+// It expects "namespace" and "dashboard" to be provided as path params. Label filters can be provided as query params
+// (see also: ExtractDashboardQueryParams). Prometheus queries are aborted when the context is done, e.g. using the request context.
+func DashboardHandlerWithContext(ctx context.Context, queryParams url.Values, pathParams map[string]string, w http.ResponseWriter, conf config.Config, logger log.LogAdapter) {
 	namespace := pathParams["namespace"]
 	dashboardName := pathParams["dashboard"]
 
@@ -30,7 +35,7 @@ func DashboardHandler(queryParams url.Values, pathParams map[string]string, w ht
 		return
 	}
 
-	dashboard, err := svc.GetDashboard(params, dashboardName)
+	dashboard, err := svc.GetDashboardWithContext(ctx, params, dashboardName)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			respondWithError(svc.Logger, w, http.StatusNotFound, err.Error())
@@ -42,17 +47,41 @@ func DashboardHandler(queryParams url.Values, pathParams map[string]string, w ht
 	respondWithJSON(svc.Logger, w, http.StatusOK, dashboard)
 }
 
-// SearchDashboardsHandler is the API handler to search for all available dashboards on pods
-// It expects "namespace" to be provided as path param. Label filters can be provided as query params
-// (see also: ExtractDashboardQueryParams)
+// SearchDashboardsHandler is the API handler to search for all available dashboards on pods, see SearchDashboardsHandlerWithContext
 func SearchDashboardsHandler(queryParams url.Values, pathParams map[string]string, w http.ResponseWriter, conf config.Config, logger log.LogAdapter) {
+	SearchDashboardsHandlerWithContext(context.Background(), queryParams, pathParams, w, conf, logger)
+}
+
+// SearchDashboardsHandlerWithContext is the API handler to search for all available dashboards on pods
+// It expects "namespace" to be provided as path param. Label filters can be provided as query params, as well as
+// the cluster to search in (see also: ExtractDashboardQueryParams); all clusters (*) isn't supported. Discovery is aborted when the context is done.
+func SearchDashboardsHandlerWithContext(ctx context.Context, queryParams url.Values, pathParams map[string]string, w http.ResponseWriter, conf config.Config, logger log.LogAdapter) {
 	namespace := pathParams["namespace"]
-	labels := queryParams.Get("labelsFilters")
 
 	var runtimes []model.Runtime
-	svc := business.NewDashboardsService(conf, logger)
-	if conf.PodsLoader != nil {
-		pods, err := conf.PodsLoader(namespace, strings.Replace(labels, ":", "=", -1))
+	defaultSvc := business.NewDashboardsService(conf, logger)
+	svc := &defaultSvc
+	labelsFilters, err := extractLabelsFilters(queryParams.Get("labelsFilters"))
+	if err != nil {
+		respondWithError(defaultSvc.Logger, w, http.StatusBadRequest, err.Error())
+		return
+	}
+	cluster := queryParams.Get("cluster")
+	if cluster == model.AllClusters {
+		// Runtimes are discovered from the pods and metrics of a single cluster
+		respondWithError(defaultSvc.Logger, w, http.StatusBadRequest, "dashboards can't be searched in all clusters at once, a single cluster is expected")
+		return
+	}
+	if cluster != "" {
+		svc, err = svc.ForCluster(cluster)
+		if err != nil {
+			respondWithError(defaultSvc.Logger, w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	// Pods are loaded from the default cluster only
+	if conf.PodsLoader != nil && svc == &defaultSvc {
+		pods, err := conf.PodsLoader(namespace, podsSelector(labelsFilters))
 		if err != nil {
 			if errors.IsNotFound(err) {
 				respondWithError(svc.Logger, w, http.StatusNotFound, err.Error())
@@ -65,8 +94,7 @@ func SearchDashboardsHandler(queryParams url.Values, pathParams map[string]strin
 	}
 
 	if len(runtimes) == 0 {
-		labelsMap := extractLabelsFilters(labels)
-		runtimes = svc.DiscoverDashboards(namespace, labelsMap)
+		runtimes = svc.DiscoverDashboardsWithContext(ctx, namespace, labelsFilters)
 	}
 
 	respondWithJSON(svc.Logger, w, http.StatusOK, runtimes)
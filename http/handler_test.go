@@ -0,0 +1,24 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiali/k-charted/config"
+	"github.com/kiali/k-charted/log"
+)
+
+func TestSearchDashboardsAllClusters(t *testing.T) {
+	assert := assert.New(t)
+
+	rr := httptest.NewRecorder()
+	queryParams := url.Values{"cluster": []string{"*"}}
+	SearchDashboardsHandler(queryParams, map[string]string{"namespace": "ns"}, rr, config.Config{}, log.LogAdapter{})
+
+	assert.Equal(http.StatusBadRequest, rr.Code)
+	assert.Contains(rr.Body.String(), "all clusters")
+}
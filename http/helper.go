@@ -2,6 +2,7 @@ package http
 
 import (
 	"errors"
+	"fmt"
 	"net/url"
 	"strconv"
 	"strings"
@@ -13,7 +14,13 @@ import (
 
 func ExtractDashboardQueryParams(queryParams url.Values, q *model.DashboardQuery) error {
 	q.FillDefaults()
-	q.LabelsFilters = extractLabelsFilters(queryParams.Get("labelsFilters"))
+	q.Cluster = queryParams.Get("cluster")
+	labelsFilters, err := extractLabelsFilters(queryParams.Get("labelsFilters"))
+	if err != nil {
+		return err
+	}
+	q.LabelsFilters = labelsFilters
+	q.Variables = extractVariables(queryParams)
 	additionalLabels := strings.Split(queryParams.Get("additionalLabels"), ",")
 	for _, additionalLabel := range additionalLabels {
 		kvPair := strings.Split(additionalLabel, ":")
@@ -30,19 +37,114 @@ func ExtractDashboardQueryParams(queryParams url.Values, q *model.DashboardQuery
 	if op == "sum" || op == "min" || op == "max" || op == "avg" || op == "stddev" || op == "stdvar" {
 		q.RawDataAggregator = op
 	}
+	if maxDataPoints := queryParams.Get("maxDataPoints"); maxDataPoints != "" {
+		if num, err := strconv.Atoi(maxDataPoints); err == nil && num > 0 {
+			q.MaxDataPoints = num
+		} else {
+			return errors.New("bad request, query parameter 'maxDataPoints' must be a positive integer")
+		}
+	}
 	return extractBaseMetricsQueryParams(queryParams, &q.MetricsQuery)
 }
 
-func extractLabelsFilters(rawString string) map[string]string {
-	labelsFilters := make(map[string]string)
-	rawFilters := strings.Split(rawString, ",")
-	for _, rawFilter := range rawFilters {
-		kvPair := strings.Split(rawFilter, ":")
-		if len(kvPair) == 2 {
-			labelsFilters[strings.TrimSpace(kvPair[0])] = strings.TrimSpace(kvPair[1])
+// extractLabelsFilters parses comma-separated labels filters, such as app:foo,version!=v1,pod=~"foo-.*".
+// Operators are ":" or "=" for equality, "!=", "=~" and "!~". Values can be double-quoted, with Go escaping, to contain commas.
+func extractLabelsFilters(rawString string) ([]prometheus.LabelMatcher, error) {
+	labelsFilters := []prometheus.LabelMatcher{}
+	rest := strings.TrimSpace(rawString)
+	for rest != "" {
+		opIdx := strings.IndexAny(rest, ":=!")
+		if opIdx < 0 {
+			return nil, fmt.Errorf("bad request, missing operator in labels filter '%s'", rest)
+		}
+		name := strings.TrimSpace(rest[:opIdx])
+		rest = rest[opIdx:]
+		var matchType prometheus.MatchType
+		switch {
+		case strings.HasPrefix(rest, "!="):
+			matchType = prometheus.MatchNotEqual
+		case strings.HasPrefix(rest, "!~"):
+			matchType = prometheus.MatchNotRegexp
+		case strings.HasPrefix(rest, "=~"):
+			matchType = prometheus.MatchRegexp
+		case strings.HasPrefix(rest, "="), strings.HasPrefix(rest, ":"):
+			matchType = prometheus.MatchEqual
+		default:
+			return nil, fmt.Errorf("bad request, invalid operator in labels filter '%s'", name+rest)
+		}
+		if matchType == prometheus.MatchEqual {
+			rest = strings.TrimSpace(rest[1:])
+		} else {
+			rest = strings.TrimSpace(rest[2:])
+		}
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := closingQuote(rest)
+			if end < 0 {
+				return nil, fmt.Errorf("bad request, unterminated quoted value for label '%s'", name)
+			}
+			unquoted, err := strconv.Unquote(rest[:end+1])
+			if err != nil {
+				return nil, fmt.Errorf("bad request, invalid quoted value for label '%s': %v", name, err)
+			}
+			value = unquoted
+			rest = strings.TrimSpace(rest[end+1:])
+			if rest != "" && !strings.HasPrefix(rest, ",") {
+				return nil, fmt.Errorf("bad request, unexpected characters after quoted value for label '%s'", name)
+			}
+		} else if sep := strings.Index(rest, ","); sep >= 0 {
+			value = strings.TrimSpace(rest[:sep])
+			rest = rest[sep:]
+		} else {
+			value = rest
+			rest = ""
+		}
+		rest = strings.TrimSpace(strings.TrimPrefix(rest, ","))
+
+		matcher, err := prometheus.NewLabelMatcher(name, matchType, value)
+		if err != nil {
+			return nil, fmt.Errorf("bad request, %v", err)
+		}
+		labelsFilters = append(labelsFilters, matcher)
+	}
+	return labelsFilters, nil
+}
+
+// closingQuote returns the index of the quote ending the quoted string s starts with, or -1
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// podsSelector converts labels filters into a Kubernetes labels selector, for the pods loader.
+// Regular expressions can't be expressed in labels selectors: such filters are left to metrics discovery.
+func podsSelector(labelsFilters []prometheus.LabelMatcher) string {
+	selectors := []string{}
+	for _, m := range labelsFilters {
+		if m.Type == prometheus.MatchEqual || m.Type == prometheus.MatchNotEqual {
+			selectors = append(selectors, m.Name+string(m.Type)+m.Value)
+		}
+	}
+	return strings.Join(selectors, ",")
+}
+
+// extractVariables reads dashboard variables values, passed as var-<name>=<value>
+func extractVariables(queryParams url.Values) map[string]string {
+	variables := make(map[string]string)
+	for key, values := range queryParams {
+		if name := strings.TrimPrefix(key, "var-"); name != key && name != "" && len(values) > 0 {
+			variables[name] = values[0]
 		}
 	}
-	return labelsFilters
+	return variables
 }
 
 func extractBaseMetricsQueryParams(queryParams url.Values, q *prometheus.MetricsQuery) error {
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/kiali/k-charted/model"
+	"github.com/kiali/k-charted/prometheus"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -30,6 +31,10 @@ func TestExtractDashboardQueryParams(t *testing.T) {
 		"labelsFilters":     []string{" app : foo  ,   version:v1 "},
 		"additionalLabels":  []string{" xx : XX  ,   yy:YY "},
 		"rawDataAggregator": []string{"avg"},
+		"var-cluster":       []string{"east"},
+		"cluster":           []string{"*"},
+		"var-":              []string{"ignored"},
+		"maxDataPoints":     []string{"500"},
 	}
 
 	params := model.DashboardQuery{Namespace: "test"}
@@ -38,9 +43,13 @@ func TestExtractDashboardQueryParams(t *testing.T) {
 	assert.Nil(err)
 	assert.Equal("test", params.Namespace)
 	assert.Equal("avg", params.RawDataAggregator)
-	assert.Len(params.LabelsFilters, 2)
-	assert.Equal("foo", params.LabelsFilters["app"])
-	assert.Equal("v1", params.LabelsFilters["version"])
+	assert.Equal([]prometheus.LabelMatcher{
+		{Name: "app", Type: prometheus.MatchEqual, Value: "foo"},
+		{Name: "version", Type: prometheus.MatchEqual, Value: "v1"},
+	}, params.LabelsFilters)
+	assert.Equal(map[string]string{"cluster": "east"}, params.Variables)
+	assert.Equal(model.AllClusters, params.Cluster)
+	assert.Equal(500, params.MaxDataPoints)
 	assert.Len(params.AdditionalLabels, 2)
 	assert.Equal(model.Aggregation{
 		Label:       "xx",
@@ -51,3 +60,36 @@ func TestExtractDashboardQueryParams(t *testing.T) {
 		DisplayName: "YY",
 	}, params.AdditionalLabels[1])
 }
+
+func TestExtractInvalidMaxDataPoints(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, value := range []string{"0", "-10", "many"} {
+		params := model.DashboardQuery{Namespace: "test"}
+		err := ExtractDashboardQueryParams(url.Values{"maxDataPoints": []string{value}}, &params)
+		assert.EqualError(err, "bad request, query parameter 'maxDataPoints' must be a positive integer")
+	}
+}
+
+func TestExtractLabelsFilters(t *testing.T) {
+	assert := assert.New(t)
+
+	filters, err := extractLabelsFilters(` app = foo, version!=v1 , pod=~"foo-(a|b),\"x\"" ,node!~n.* `)
+	assert.Nil(err)
+	assert.Equal([]prometheus.LabelMatcher{
+		{Name: "app", Type: prometheus.MatchEqual, Value: "foo"},
+		{Name: "version", Type: prometheus.MatchNotEqual, Value: "v1"},
+		{Name: "pod", Type: prometheus.MatchRegexp, Value: `foo-(a|b),"x"`},
+		{Name: "node", Type: prometheus.MatchNotRegexp, Value: "n.*"},
+	}, filters)
+	assert.Equal("app=foo,version!=v1", podsSelector(filters))
+
+	filters, err = extractLabelsFilters("")
+	assert.Nil(err)
+	assert.Empty(filters)
+
+	for _, invalid := range []string{"app", `app="foo`, `app="foo"bar`, "app=~(", `a"b=c`, "app!foo"} {
+		_, err = extractLabelsFilters(invalid)
+		assert.NotNil(err, invalid)
+	}
+}
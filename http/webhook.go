@@ -0,0 +1,55 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	admission "k8s.io/api/admission/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/kiali/k-charted/business"
+	"github.com/kiali/k-charted/config"
+	"github.com/kiali/k-charted/kubernetes/v1beta1"
+	"github.com/kiali/k-charted/log"
+)
+
+// ValidatingWebhookHandler is the handler for a Kubernetes validating admission webhook on MonitoringDashboard resources.
+// It expects an AdmissionReview as request body, and responds with the same AdmissionReview filled with the validation result.
+// Both admission.k8s.io/v1 and v1beta1 are supported as they share the same structure.
+func ValidatingWebhookHandler(body io.Reader, w http.ResponseWriter, conf config.Config, logger log.LogAdapter) {
+	svc := business.NewDashboardsService(conf, logger)
+
+	raw, err := ioutil.ReadAll(body)
+	if err != nil {
+		respondWithError(svc.Logger, w, http.StatusBadRequest, err.Error())
+		return
+	}
+	review := admission.AdmissionReview{}
+	if err = json.Unmarshal(raw, &review); err != nil || review.Request == nil {
+		respondWithError(svc.Logger, w, http.StatusBadRequest, "bad request, AdmissionReview expected")
+		return
+	}
+
+	request := review.Request
+	response := admission.AdmissionResponse{UID: request.UID, Allowed: true}
+	if request.Operation == admission.Create || request.Operation == admission.Update {
+		errs, err := svc.ValidateDashboard(request.Namespace, request.Object.Raw)
+		if err != nil {
+			response.Allowed = false
+			response.Result = &meta_v1.Status{Status: meta_v1.StatusFailure, Message: err.Error(), Reason: meta_v1.StatusReasonBadRequest, Code: http.StatusBadRequest}
+		} else if len(errs) > 0 {
+			response.Allowed = false
+			status := errors.NewInvalid(schema.GroupKind{Group: v1beta1.GroupVersion.Group, Kind: "MonitoringDashboard"}, request.Name, errs).Status()
+			response.Result = &status
+		}
+	}
+
+	respondWithJSON(svc.Logger, w, http.StatusOK, admission.AdmissionReview{
+		TypeMeta: review.TypeMeta,
+		Response: &response,
+	})
+}
@@ -0,0 +1,56 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	admission "k8s.io/api/admission/v1beta1"
+
+	"github.com/kiali/k-charted/config"
+	"github.com/kiali/k-charted/log"
+)
+
+func runWebhook(t *testing.T, object string) admission.AdmissionReview {
+	body := `{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview","request":{"uid":"123","name":"d","namespace":"ns","operation":"CREATE","object":` + object + `}}`
+	rr := httptest.NewRecorder()
+	conf := config.Config{DashboardSources: []string{"builtin"}}
+	ValidatingWebhookHandler(strings.NewReader(body), rr, conf, log.LogAdapter{})
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var review admission.AdmissionReview
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &review))
+	assert.Equal(t, "admission.k8s.io/v1", review.APIVersion)
+	assert.Equal(t, "123", string(review.Response.UID))
+	return review
+}
+
+func TestWebhookAllowsValidDashboard(t *testing.T) {
+	review := runWebhook(t, `{"metadata":{"name":"d"},"spec":{"title":"D","items":[
+		{"chart":{"name":"c1","dataType":"raw","metrics":[{"metricName":"m"}]}},
+		{"include":"go"}
+	]}}`)
+	assert.True(t, review.Response.Allowed)
+}
+
+func TestWebhookRejectsInvalidDashboard(t *testing.T) {
+	assert := assert.New(t)
+
+	review := runWebhook(t, `{"metadata":{"name":"d"},"spec":{"title":"D","items":[
+		{"chart":{"name":"c1","dataType":"foo","metrics":[{"metricName":"m"}]}},
+		{"include":"missing"}
+	]}}`)
+	assert.False(review.Response.Allowed)
+	assert.Len(review.Response.Result.Details.Causes, 2)
+	assert.Equal("spec.items[0].chart.dataType", review.Response.Result.Details.Causes[0].Field)
+	assert.Equal("spec.items[1].include", review.Response.Result.Details.Causes[1].Field)
+}
+
+func TestWebhookBadRequest(t *testing.T) {
+	rr := httptest.NewRecorder()
+	ValidatingWebhookHandler(strings.NewReader("{}"), rr, config.Config{}, log.LogAdapter{})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,61 @@
+package kubernetes
+
+import (
+	"sort"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/kiali/k-charted/kubernetes/v1beta1"
+)
+
+var (
+	builtInOnce   sync.Once
+	builtInClient *BuiltInClient
+	builtInErr    error
+)
+
+// BuiltInClient is a ClientInterface implementation serving the dashboards library compiled into k-charted.
+// Built-in dashboards are not bound to any namespace: the same ones are returned for every namespace.
+type BuiltInClient struct {
+	ClientInterface
+	dashboards map[string]v1beta1.MonitoringDashboard
+}
+
+// GetBuiltInClient returns the BuiltInClient, parsing the library on first call
+func GetBuiltInClient() (*BuiltInClient, error) {
+	builtInOnce.Do(func() {
+		dashboards := make(map[string]v1beta1.MonitoringDashboard)
+		for _, content := range builtInDashboards {
+			parsed, err := parseDashboards(content, "")
+			if err != nil {
+				builtInErr = err
+				return
+			}
+			for _, d := range parsed {
+				dashboards[d.Name] = d
+			}
+		}
+		builtInClient = &BuiltInClient{dashboards: dashboards}
+	})
+	return builtInClient, builtInErr
+}
+
+// GetDashboard returns a MonitoringDashboard for the given name
+func (in *BuiltInClient) GetDashboard(namespace, name string) (*v1beta1.MonitoringDashboard, error) {
+	if dashboard, ok := in.dashboards[name]; ok {
+		return dashboard.DeepCopy(), nil
+	}
+	return nil, errors.NewNotFound(schema.GroupResource{Group: v1beta1.GroupVersion.Group, Resource: monitoringDashboardsResource}, name)
+}
+
+// GetDashboards returns all built-in MonitoringDashboards
+func (in *BuiltInClient) GetDashboards(namespace string) ([]v1beta1.MonitoringDashboard, error) {
+	dashboards := make([]v1beta1.MonitoringDashboard, 0, len(in.dashboards))
+	for _, d := range in.dashboards {
+		dashboards = append(dashboards, *d.DeepCopy())
+	}
+	sort.Slice(dashboards, func(i, j int) bool { return dashboards[i].Name < dashboards[j].Name })
+	return dashboards, nil
+}
@@ -0,0 +1,645 @@
+package kubernetes
+
+// Built-in dashboards, compiled into the binary and used as lowest-priority source.
+// Any of them can be overridden by a MonitoringDashboard of the same name, e.g. in the global namespace.
+// Composition (include) is used so that discovery only shows the most specific dashboard: for instance "springboot" includes "jvm".
+// They are written without apiVersion, hence in the v1alpha1 format.
+var builtInDashboards = []string{
+	goDashboard,
+	jvmDashboard,
+	microProfileDashboard,
+	nodejsDashboard,
+	envoyDashboard,
+	vertxDashboards,
+	quarkusDashboard,
+	springBootDashboard,
+}
+
+const goDashboard = `
+metadata:
+  name: go
+spec:
+  title: Go Metrics
+  runtime: Go
+  discoverOn: "go_info"
+  items:
+  - chart:
+      name: "CPU ratio"
+      spans: 6
+      metrics:
+      - metricName: "process_cpu_seconds_total"
+        displayName: "CPU ratio"
+      dataType: "rate"
+      aggregations:
+      - label: "pod"
+        displayName: "Pod"
+  - chart:
+      name: "RSS Memory"
+      unit: "bytes"
+      spans: 6
+      metrics:
+      - metricName: "process_resident_memory_bytes"
+        displayName: "RSS Memory"
+      dataType: "raw"
+      aggregations:
+      - label: "pod"
+        displayName: "Pod"
+  - chart:
+      name: "Goroutines"
+      spans: 6
+      metrics:
+      - metricName: "go_goroutines"
+        displayName: "Goroutines"
+      dataType: "raw"
+      aggregations:
+      - label: "pod"
+        displayName: "Pod"
+  - chart:
+      name: "Heap allocation rate"
+      unit: "bytes/s"
+      spans: 6
+      metrics:
+      - metricName: "go_memstats_alloc_bytes_total"
+        displayName: "Heap allocation rate"
+      dataType: "rate"
+      aggregations:
+      - label: "pod"
+        displayName: "Pod"
+  - chart:
+      name: "GC rate"
+      spans: 6
+      metrics:
+      - metricName: "go_gc_duration_seconds_count"
+        displayName: "GC rate"
+      dataType: "rate"
+      aggregations:
+      - label: "pod"
+        displayName: "Pod"
+  - chart:
+      name: "Next GC"
+      unit: "bytes"
+      spans: 6
+      metrics:
+      - metricName: "go_memstats_next_gc_bytes"
+        displayName: "Next GC"
+      dataType: "raw"
+      aggregations:
+      - label: "pod"
+        displayName: "Pod"
+`
+
+// jvmDashboard is based on Micrometer JVM metrics, used by several frameworks
+const jvmDashboard = `
+metadata:
+  name: jvm
+spec:
+  title: JVM Metrics
+  runtime: JVM
+  discoverOn: "jvm_threads_live_threads"
+  items:
+  - chart:
+      name: "Memory used"
+      unit: "bytes"
+      spans: 6
+      metrics:
+      - metricName: "jvm_memory_used_bytes"
+        displayName: "Memory used"
+      dataType: "raw"
+      aggregations:
+      - label: "area"
+        displayName: "Area"
+      - label: "id"
+        displayName: "Space"
+      - label: "pod"
+        displayName: "Pod"
+  - chart:
+      name: "Loaded classes"
+      spans: 6
+      metrics:
+      - metricName: "jvm_classes_loaded_classes"
+        displayName: "Loaded classes"
+      dataType: "raw"
+      aggregations:
+      - label: "pod"
+        displayName: "Pod"
+  - chart:
+      name: "Threads"
+      spans: 6
+      metrics:
+      - metricName: "jvm_threads_live_threads"
+        displayName: "Live"
+      - metricName: "jvm_threads_daemon_threads"
+        displayName: "Daemon"
+      - metricName: "jvm_threads_peak_threads"
+        displayName: "Peak"
+      dataType: "raw"
+      aggregations:
+      - label: "pod"
+        displayName: "Pod"
+  - chart:
+      name: "GC pauses rate"
+      spans: 6
+      metrics:
+      - metricName: "jvm_gc_pause_seconds_count"
+        displayName: "GC pauses rate"
+      dataType: "rate"
+      aggregations:
+      - label: "action"
+        displayName: "Action"
+      - label: "cause"
+        displayName: "Cause"
+      - label: "pod"
+        displayName: "Pod"
+`
+
+const microProfileDashboard = `
+metadata:
+  name: microprofile-1.1
+spec:
+  title: MicroProfile Metrics
+  runtime: MicroProfile
+  discoverOn: "base_thread_count"
+  items:
+  - chart:
+      name: "Current loaded classes"
+      spans: 6
+      metrics:
+      - metricName: "base_classloader_loadedClasses_count"
+        displayName: "Current loaded classes"
+      dataType: "raw"
+      aggregations:
+      - label: "pod"
+        displayName: "Pod"
+  - chart:
+      name: "Unloaded classes"
+      spans: 6
+      metrics:
+      - metricName: "base_classloader_unloadedClasses_total"
+        displayName: "Unloaded classes"
+      dataType: "raw"
+      aggregations:
+      - label: "pod"
+        displayName: "Pod"
+  - chart:
+      name: "Thread count"
+      spans: 4
+      metrics:
+      - metricName: "base_thread_count"
+        displayName: "Thread count"
+      dataType: "raw"
+      aggregations:
+      - label: "pod"
+        displayName: "Pod"
+  - chart:
+      name: "Thread max count"
+      spans: 4
+      metrics:
+      - metricName: "base_thread_max_count"
+        displayName: "Thread max count"
+      dataType: "raw"
+      aggregations:
+      - label: "pod"
+        displayName: "Pod"
+  - chart:
+      name: "Thread daemon count"
+      spans: 4
+      metrics:
+      - metricName: "base_thread_daemon_count"
+        displayName: "Thread daemon count"
+      dataType: "raw"
+      aggregations:
+      - label: "pod"
+        displayName: "Pod"
+  - chart:
+      name: "Heap"
+      unit: "bytes"
+      spans: 6
+      metrics:
+      - metricName: "base_memory_usedHeap_bytes"
+        displayName: "Used"
+      - metricName: "base_memory_committedHeap_bytes"
+        displayName: "Committed"
+      - metricName: "base_memory_maxHeap_bytes"
+        displayName: "Max"
+      dataType: "raw"
+      aggregations:
+      - label: "pod"
+        displayName: "Pod"
+  - chart:
+      name: "System load average"
+      spans: 6
+      metrics:
+      - metricName: "base_cpu_systemLoadAverage"
+        displayName: "System load average"
+      dataType: "raw"
+      aggregations:
+      - label: "pod"
+        displayName: "Pod"
+`
+
+const nodejsDashboard = `
+metadata:
+  name: nodejs
+spec:
+  title: Node.js Metrics
+  runtime: Node.js
+  discoverOn: "nodejs_active_handles_total"
+  items:
+  - chart:
+      name: "Active handles"
+      spans: 6
+      metrics:
+      - metricName: "nodejs_active_handles_total"
+        displayName: "Active handles"
+      dataType: "raw"
+      aggregations:
+      - label: "pod"
+        displayName: "Pod"
+  - chart:
+      name: "Active requests"
+      spans: 6
+      metrics:
+      - metricName: "nodejs_active_requests_total"
+        displayName: "Active requests"
+      dataType: "raw"
+      aggregations:
+      - label: "pod"
+        displayName: "Pod"
+  - chart:
+      name: "Event loop lag"
+      unit: "seconds"
+      spans: 6
+      metrics:
+      - metricName: "nodejs_eventloop_lag_seconds"
+        displayName: "Event loop lag"
+      dataType: "raw"
+      aggregations:
+      - label: "pod"
+        displayName: "Pod"
+  - chart:
+      name: "Heap"
+      unit: "bytes"
+      spans: 6
+      metrics:
+      - metricName: "nodejs_heap_size_used_bytes"
+        displayName: "Used"
+      - metricName: "nodejs_heap_size_total_bytes"
+        displayName: "Total"
+      dataType: "raw"
+      aggregations:
+      - label: "pod"
+        displayName: "Pod"
+  - chart:
+      name: "External memory"
+      unit: "bytes"
+      spans: 6
+      metrics:
+      - metricName: "nodejs_external_memory_bytes"
+        displayName: "External memory"
+      dataType: "raw"
+      aggregations:
+      - label: "pod"
+        displayName: "Pod"
+`
+
+const envoyDashboard = `
+metadata:
+  name: envoy
+spec:
+  title: Envoy Metrics
+  runtime: Envoy
+  discoverOn: "envoy_server_uptime"
+  items:
+  - chart:
+      name: "Pods uptime"
+      unit: "seconds"
+      spans: 4
+      metrics:
+      - metricName: "envoy_server_uptime"
+        displayName: "Pods uptime"
+      dataType: "raw"
+      aggregations:
+      - label: "pod"
+        displayName: "Pod"
+  - chart:
+      name: "Allocated memory"
+      unit: "bytes"
+      spans: 4
+      metrics:
+      - metricName: "envoy_server_memory_allocated"
+        displayName: "Allocated memory"
+      dataType: "raw"
+      aggregations:
+      - label: "pod"
+        displayName: "Pod"
+  - chart:
+      name: "Heap size"
+      unit: "bytes"
+      spans: 4
+      metrics:
+      - metricName: "envoy_server_memory_heap_size"
+        displayName: "Heap size"
+      dataType: "raw"
+      aggregations:
+      - label: "pod"
+        displayName: "Pod"
+  - chart:
+      name: "Upstream active connections"
+      spans: 6
+      metrics:
+      - metricName: "envoy_cluster_upstream_cx_active"
+        displayName: "Upstream active connections"
+      dataType: "raw"
+      aggregations:
+      - label: "cluster_name"
+        displayName: "Cluster"
+      - label: "pod"
+        displayName: "Pod"
+  - chart:
+      name: "Upstream requests rate"
+      spans: 6
+      metrics:
+      - metricName: "envoy_cluster_upstream_rq_total"
+        displayName: "Upstream requests rate"
+      dataType: "rate"
+      aggregations:
+      - label: "cluster_name"
+        displayName: "Cluster"
+      - label: "pod"
+        displayName: "Pod"
+  - chart:
+      name: "Downstream active connections"
+      spans: 6
+      metrics:
+      - metricName: "envoy_listener_downstream_cx_active"
+        displayName: "Downstream active connections"
+      dataType: "raw"
+      aggregations:
+      - label: "listener_address"
+        displayName: "Listener"
+      - label: "pod"
+        displayName: "Pod"
+  - chart:
+      name: "Downstream HTTP requests rate"
+      spans: 6
+      metrics:
+      - metricName: "envoy_http_downstream_rq_total"
+        displayName: "Downstream HTTP requests rate"
+      dataType: "rate"
+      aggregations:
+      - label: "http_conn_manager_prefix"
+        displayName: "Connection manager"
+      - label: "pod"
+        displayName: "Pod"
+`
+
+const vertxDashboards = `
+metadata:
+  name: vertx-server
+spec:
+  title: Vert.x Server Metrics
+  runtime: Vert.x
+  discoverOn: "vertx_http_server_connections"
+  items:
+  - chart:
+      name: "Server response time"
+      unit: "seconds"
+      spans: 6
+      metrics:
+      - metricName: "vertx_http_server_responseTime_seconds"
+        displayName: "Server response time"
+      dataType: "histogram"
+      aggregations:
+      - label: "path"
+        displayName: "Path"
+      - label: "method"
+        displayName: "Method"
+  - chart:
+      name: "Server request count rate"
+      unit: "ops"
+      spans: 6
+      metrics:
+      - metricName: "vertx_http_server_requestCount_total"
+        displayName: "Server request count rate"
+      dataType: "rate"
+      aggregations:
+      - label: "code"
+        displayName: "Error code"
+      - label: "path"
+        displayName: "Path"
+      - label: "method"
+        displayName: "Method"
+  - chart:
+      name: "Server active connections"
+      spans: 6
+      metrics:
+      - metricName: "vertx_http_server_connections"
+        displayName: "Server active connections"
+      dataType: "raw"
+  - chart:
+      name: "Server active websockets"
+      spans: 6
+      metrics:
+      - metricName: "vertx_http_server_wsConnections"
+        displayName: "Server active websockets"
+      dataType: "raw"
+  - include: "jvm"
+---
+metadata:
+  name: vertx-client
+spec:
+  title: Vert.x Client Metrics
+  runtime: Vert.x
+  discoverOn: "vertx_http_client_connections"
+  items:
+  - chart:
+      name: "Client response time"
+      unit: "seconds"
+      spans: 6
+      metrics:
+      - metricName: "vertx_http_client_responseTime_seconds"
+        displayName: "Client response time"
+      dataType: "histogram"
+      aggregations:
+      - label: "path"
+        displayName: "Path"
+      - label: "method"
+        displayName: "Method"
+  - chart:
+      name: "Client request count rate"
+      unit: "ops"
+      spans: 6
+      metrics:
+      - metricName: "vertx_http_client_requestCount_total"
+        displayName: "Client request count rate"
+      dataType: "rate"
+      aggregations:
+      - label: "code"
+        displayName: "Error code"
+      - label: "path"
+        displayName: "Path"
+      - label: "method"
+        displayName: "Method"
+  - chart:
+      name: "Client active connections"
+      spans: 6
+      metrics:
+      - metricName: "vertx_http_client_connections"
+        displayName: "Client active connections"
+      dataType: "raw"
+  - chart:
+      name: "Client queue pending"
+      spans: 6
+      metrics:
+      - metricName: "vertx_http_client_queue_pending"
+        displayName: "Client queue pending"
+      dataType: "raw"
+---
+metadata:
+  name: vertx-eventbus
+spec:
+  title: Vert.x Eventbus Metrics
+  runtime: Vert.x
+  discoverOn: "vertx_eventbus_handlers"
+  items:
+  - chart:
+      name: "Event bus handlers"
+      spans: 6
+      metrics:
+      - metricName: "vertx_eventbus_handlers"
+        displayName: "Event bus handlers"
+      dataType: "raw"
+      aggregations:
+      - label: "address"
+        displayName: "Eventbus address"
+  - chart:
+      name: "Event bus pending messages"
+      spans: 6
+      metrics:
+      - metricName: "vertx_eventbus_pending"
+        displayName: "Event bus pending messages"
+      dataType: "raw"
+      aggregations:
+      - label: "address"
+        displayName: "Eventbus address"
+  - chart:
+      name: "Event bus processing time"
+      unit: "seconds"
+      spans: 6
+      metrics:
+      - metricName: "vertx_eventbus_processingTime_seconds"
+        displayName: "Event bus processing time"
+      dataType: "histogram"
+      aggregations:
+      - label: "address"
+        displayName: "Eventbus address"
+  - chart:
+      name: "Event bus bytes read"
+      unit: "bytes"
+      spans: 6
+      metrics:
+      - metricName: "vertx_eventbus_bytesRead_bytes"
+        displayName: "Event bus bytes read"
+      dataType: "histogram"
+      aggregations:
+      - label: "address"
+        displayName: "Eventbus address"
+`
+
+// quarkusDashboard is based on SmallRye metrics, which also expose MicroProfile base metrics
+const quarkusDashboard = `
+metadata:
+  name: quarkus
+spec:
+  title: Quarkus Metrics
+  runtime: Quarkus
+  discoverOn: "vendor_memory_usedNonHeap_bytes"
+  items:
+  - chart:
+      name: "Non-heap"
+      unit: "bytes"
+      spans: 6
+      metrics:
+      - metricName: "vendor_memory_usedNonHeap_bytes"
+        displayName: "Used"
+      - metricName: "vendor_memory_committedNonHeap_bytes"
+        displayName: "Committed"
+      dataType: "raw"
+      aggregations:
+      - label: "pod"
+        displayName: "Pod"
+  - chart:
+      name: "Process CPU load"
+      unit: "%"
+      spans: 6
+      metrics:
+      - metricName: "vendor_cpu_processCpuLoad_percent"
+        displayName: "Process CPU load"
+      dataType: "raw"
+      aggregations:
+      - label: "pod"
+        displayName: "Pod"
+  - include: "microprofile-1.1"
+`
+
+// springBootDashboard is based on Spring Boot Actuator (Micrometer) metrics
+const springBootDashboard = `
+metadata:
+  name: springboot
+spec:
+  title: Spring Boot Metrics
+  runtime: Spring Boot
+  discoverOn: "application_ready_time_seconds"
+  items:
+  - chart:
+      name: "Request rate"
+      unit: "ops"
+      spans: 6
+      metrics:
+      - metricName: "http_server_requests_seconds_count"
+        displayName: "Request rate"
+      dataType: "rate"
+      aggregations:
+      - label: "uri"
+        displayName: "URI"
+      - label: "method"
+        displayName: "Method"
+      - label: "status"
+        displayName: "Status"
+  - chart:
+      name: "Request duration"
+      unit: "seconds"
+      spans: 6
+      metrics:
+      - metricName: "http_server_requests_seconds"
+        displayName: "Request duration"
+      dataType: "histogram"
+      aggregations:
+      - label: "uri"
+        displayName: "URI"
+      - label: "method"
+        displayName: "Method"
+      - label: "status"
+        displayName: "Status"
+  - chart:
+      name: "Tomcat sessions"
+      spans: 6
+      metrics:
+      - metricName: "tomcat_sessions_active_current_sessions"
+        displayName: "Active sessions"
+      dataType: "raw"
+      aggregations:
+      - label: "pod"
+        displayName: "Pod"
+  - chart:
+      name: "Tomcat busy threads"
+      spans: 6
+      metrics:
+      - metricName: "tomcat_threads_busy_threads"
+        displayName: "Busy threads"
+      dataType: "raw"
+      aggregations:
+      - label: "pod"
+        displayName: "Pod"
+  - include: "jvm"
+`
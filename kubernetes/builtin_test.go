@@ -0,0 +1,74 @@
+package kubernetes
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/kiali/k-charted/kubernetes/v1beta1"
+	"github.com/kiali/k-charted/kubernetes/v1beta1/validation"
+)
+
+func TestBuiltInDashboards(t *testing.T) {
+	assert := assert.New(t)
+
+	client, err := GetBuiltInClient()
+	assert.Nil(err)
+
+	all, err := client.GetDashboards("any-namespace")
+	assert.Nil(err)
+	assert.Len(all, 10)
+
+	names := make(map[string]bool)
+	discoverOn := make(map[string]string)
+	for _, d := range all {
+		names[d.Name] = true
+		assert.NotEmpty(d.Spec.Title, d.Name)
+		assert.NotEmpty(d.Spec.Runtime, d.Name)
+		assert.NotEmpty(d.Spec.DiscoverOn, d.Name)
+		other, exists := discoverOn[d.Spec.DiscoverOn]
+		assert.False(exists, "%s and %s are discovered on the same metric", d.Name, other)
+		discoverOn[d.Spec.DiscoverOn] = d.Name
+	}
+	for _, d := range all {
+		dashboard := d
+		errs := validation.ValidateDashboard(&dashboard, func(name string) (*v1beta1.MonitoringDashboard, error) {
+			return client.GetDashboard("any-namespace", name)
+		})
+		assert.Empty(errs, d.Name)
+		for _, item := range d.Spec.Items {
+			if item.Include != "" {
+				assert.True(names[strings.Split(item.Include, "$")[0]], "%s includes unknown dashboard %s", d.Name, item.Include)
+				continue
+			}
+			if !assert.NotNil(item.Chart, d.Name) {
+				continue
+			}
+			assert.NotEmpty(item.Chart.Name, d.Name)
+			assert.NotEmpty(item.Chart.Metrics, d.Name)
+			assert.Contains([]v1beta1.DataType{v1beta1.Raw, v1beta1.Rate, v1beta1.Histogram}, item.Chart.Query.DataType, d.Name)
+		}
+	}
+}
+
+func TestBuiltInGetDashboard(t *testing.T) {
+	assert := assert.New(t)
+
+	client, err := GetBuiltInClient()
+	assert.Nil(err)
+
+	d, err := client.GetDashboard("any-namespace", "go")
+	assert.Nil(err)
+	assert.Equal("Go Metrics", d.Spec.Title)
+	assert.Equal("go_info", d.Spec.DiscoverOn)
+
+	// Returned dashboards are copies
+	d.Spec.Title = "modified"
+	d, _ = client.GetDashboard("any-namespace", "go")
+	assert.Equal("Go Metrics", d.Spec.Title)
+
+	_, err = client.GetDashboard("any-namespace", "unknown")
+	assert.True(errors.IsNotFound(err))
+}
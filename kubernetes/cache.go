@@ -0,0 +1,118 @@
+package kubernetes
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/kiali/k-charted/config/extconfig"
+	"github.com/kiali/k-charted/kubernetes/v1beta1"
+)
+
+const monitoringDashboardsResource = "monitoringdashboards"
+
+var (
+	cachedClients     = make(map[extconfig.KubernetesConfig]*CachedClient)
+	cachedClientsLock sync.Mutex
+)
+
+// CachedClient is a ClientInterface implementation that serves MonitoringDashboards from an in-memory cache,
+// kept up to date by an informer watching resources in all namespaces, in the version served by the API server.
+// Until the cache is synced, calls are delegated to the live client.
+type CachedClient struct {
+	ClientInterface
+	live     ClientInterface
+	informer cache.SharedIndexInformer
+	stop     chan struct{}
+}
+
+// GetCachedClient returns the CachedClient shared for the given configuration, creating and starting it on first call.
+// Sharing is necessary as DashboardsService instances are typically short-lived (e.g. one per HTTP request).
+func GetCachedClient(cfg extconfig.KubernetesConfig) (*CachedClient, error) {
+	cachedClientsLock.Lock()
+	defer cachedClientsLock.Unlock()
+	if client, ok := cachedClients[cfg]; ok {
+		return client, nil
+	}
+	live, err := NewClientWithConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	lw := cache.NewListWatchFromClient(live.client, monitoringDashboardsResource, meta_v1.NamespaceAll, fields.Everything())
+	client := newCachedClient(lw, newDashboard(live.version), live, cfg.CacheResyncPeriod)
+	cachedClients[cfg] = client
+	return client, nil
+}
+
+func newCachedClient(lw cache.ListerWatcher, objType runtime.Object, live ClientInterface, resync time.Duration) *CachedClient {
+	informer := cache.NewSharedIndexInformer(lw, objType, resync, cache.Indexers{
+		cache.NamespaceIndex: cache.MetaNamespaceIndexFunc,
+	})
+	client := &CachedClient{
+		live:     live,
+		informer: informer,
+		stop:     make(chan struct{}),
+	}
+	go informer.Run(client.stop)
+	return client
+}
+
+// Stop stops watching resources. The client must not be used afterwards.
+func (in *CachedClient) Stop() {
+	cachedClientsLock.Lock()
+	defer cachedClientsLock.Unlock()
+	for cfg, client := range cachedClients {
+		if client == in {
+			delete(cachedClients, cfg)
+		}
+	}
+	close(in.stop)
+}
+
+// WaitForSync blocks until the cache is synced or the timeout expires. It returns false in the latter case.
+func (in *CachedClient) WaitForSync(timeout time.Duration) bool {
+	stop := make(chan struct{})
+	timer := time.AfterFunc(timeout, func() { close(stop) })
+	defer timer.Stop()
+	return cache.WaitForCacheSync(stop, in.informer.HasSynced)
+}
+
+// GetDashboard returns a MonitoringDashboard for the given name
+func (in *CachedClient) GetDashboard(namespace, name string) (*v1beta1.MonitoringDashboard, error) {
+	if !in.informer.HasSynced() {
+		return in.live.GetDashboard(namespace, name)
+	}
+	obj, exists, err := in.informer.GetIndexer().GetByKey(namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(schema.GroupResource{Group: v1beta1.GroupVersion.Group, Resource: monitoringDashboardsResource}, name)
+	}
+	// Callers may modify the returned object, so it must not be shared with the cache: conversion always returns a copy
+	return toV1beta1(obj)
+}
+
+// GetDashboards returns all MonitoringDashboards from the given namespace
+func (in *CachedClient) GetDashboards(namespace string) ([]v1beta1.MonitoringDashboard, error) {
+	if !in.informer.HasSynced() {
+		return in.live.GetDashboards(namespace)
+	}
+	objs, err := in.informer.GetIndexer().ByIndex(cache.NamespaceIndex, namespace)
+	if err != nil {
+		return nil, err
+	}
+	dashboards := make([]v1beta1.MonitoringDashboard, 0, len(objs))
+	for _, obj := range objs {
+		if dashboard, err := toV1beta1(obj); err == nil {
+			dashboards = append(dashboards, *dashboard)
+		}
+	}
+	return dashboards, nil
+}
@@ -0,0 +1,142 @@
+package kubernetes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/kiali/k-charted/kubernetes/mock"
+	"github.com/kiali/k-charted/kubernetes/v1alpha1"
+	"github.com/kiali/k-charted/kubernetes/v1beta1"
+)
+
+func fakeDashboard(namespace, name, title string) v1alpha1.MonitoringDashboard {
+	return v1alpha1.MonitoringDashboard{
+		ObjectMeta: meta_v1.ObjectMeta{Namespace: namespace, Name: name, ResourceVersion: "1"},
+		Spec:       v1alpha1.MonitoringDashboardSpec{Title: title},
+	}
+}
+
+func setupCachedClient(initial ...v1alpha1.MonitoringDashboard) (*CachedClient, *watch.FakeWatcher, *mock.ClientMock) {
+	watcher := watch.NewFake()
+	lw := &cache.ListWatch{
+		ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+			return &v1alpha1.MonitoringDashboardsList{ListMeta: meta_v1.ListMeta{ResourceVersion: "1"}, Items: initial}, nil
+		},
+		WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+			return watcher, nil
+		},
+	}
+	live := new(mock.ClientMock)
+	return newCachedClient(lw, &v1alpha1.MonitoringDashboard{}, live, 0), watcher, live
+}
+
+func TestCachedClientServesFromMemory(t *testing.T) {
+	assert := assert.New(t)
+
+	client, _, live := setupCachedClient(fakeDashboard("ns1", "d1", "Dashboard 1"), fakeDashboard("ns2", "d2", "Dashboard 2"))
+	defer client.Stop()
+	assert.True(client.WaitForSync(5 * time.Second))
+
+	d, err := client.GetDashboard("ns1", "d1")
+	assert.Nil(err)
+	assert.Equal("Dashboard 1", d.Spec.Title)
+
+	_, err = client.GetDashboard("ns1", "d2")
+	assert.True(errors.IsNotFound(err))
+
+	all, err := client.GetDashboards("ns2")
+	assert.Nil(err)
+	assert.Len(all, 1)
+	assert.Equal("d2", all[0].Name)
+
+	live.AssertNotCalled(t, "GetDashboard")
+	live.AssertNotCalled(t, "GetDashboards")
+}
+
+func TestCachedClientReturnsCopies(t *testing.T) {
+	assert := assert.New(t)
+
+	client, _, _ := setupCachedClient(fakeDashboard("ns1", "d1", "Dashboard 1"))
+	defer client.Stop()
+	assert.True(client.WaitForSync(5 * time.Second))
+
+	d, _ := client.GetDashboard("ns1", "d1")
+	d.Spec.Title = "modified"
+
+	d, _ = client.GetDashboard("ns1", "d1")
+	assert.Equal("Dashboard 1", d.Spec.Title)
+}
+
+func TestCachedClientWatchUpdates(t *testing.T) {
+	assert := assert.New(t)
+
+	client, watcher, _ := setupCachedClient(fakeDashboard("ns1", "d1", "Dashboard 1"))
+	defer client.Stop()
+	assert.True(client.WaitForSync(5 * time.Second))
+
+	updated := fakeDashboard("ns1", "d1", "Updated")
+	updated.ResourceVersion = "2"
+	watcher.Modify(&updated)
+	added := fakeDashboard("ns1", "d3", "Dashboard 3")
+	added.ResourceVersion = "3"
+	watcher.Add(&added)
+
+	assert.Eventually(func() bool {
+		all, _ := client.GetDashboards("ns1")
+		return len(all) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	d, err := client.GetDashboard("ns1", "d1")
+	assert.Nil(err)
+	assert.Equal("Updated", d.Spec.Title)
+
+	watcher.Delete(&added)
+	assert.Eventually(func() bool {
+		_, err := client.GetDashboard("ns1", "d3")
+		return errors.IsNotFound(err)
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestCachedClientV1beta1ListWatch(t *testing.T) {
+	assert := assert.New(t)
+
+	// As served by the API server for the current CRD; the informer decodes lists and watch events without a typed target
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("watch") == "true" {
+			return
+		}
+		_, _ = w.Write([]byte(`{"apiVersion":"monitoring.kiali.io/v1beta1","kind":"MonitoringDashboardList","metadata":{"resourceVersion":"1"},"items":[{"apiVersion":"monitoring.kiali.io/v1beta1","kind":"MonitoringDashboard","metadata":{"name":"go","namespace":"ns1","resourceVersion":"1"},"spec":{"title":"Go Metrics"}}]}`))
+	}))
+	defer server.Close()
+
+	scheme, err := newScheme()
+	assert.Nil(err)
+	restClient, err := newClientForAPI(&rest.Config{Host: server.URL}, v1beta1.GroupVersion, scheme)
+	assert.Nil(err)
+	lw := cache.NewListWatchFromClient(restClient, monitoringDashboardsResource, meta_v1.NamespaceAll, fields.Everything())
+
+	list, err := lw.List(meta_v1.ListOptions{})
+	assert.Nil(err)
+	assert.IsType(&v1beta1.MonitoringDashboardList{}, list)
+
+	live := new(mock.ClientMock)
+	client := newCachedClient(lw, newDashboard(v1beta1.GroupVersion), live, 0)
+	defer client.Stop()
+	assert.True(client.WaitForSync(5 * time.Second))
+
+	d, err := client.GetDashboard("ns1", "go")
+	assert.Nil(err)
+	assert.Equal("Go Metrics", d.Spec.Title)
+	live.AssertNotCalled(t, "GetDashboard")
+}
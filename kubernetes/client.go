@@ -1,85 +1,192 @@
 package kubernetes
 
 import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
 
+	"github.com/kiali/k-charted/config/extconfig"
 	"github.com/kiali/k-charted/kubernetes/v1alpha1"
+	"github.com/kiali/k-charted/kubernetes/v1beta1"
 )
 
+// servedVersions caches the MonitoringDashboard version discovered per configuration
+var servedVersions sync.Map
+
 // ClientInterface for mocks (only mocked function are necessary here)
 type ClientInterface interface {
-	GetDashboard(namespace string, name string) (*v1alpha1.MonitoringDashboard, error)
-	GetDashboards(namespace string) ([]v1alpha1.MonitoringDashboard, error)
+	GetDashboard(namespace string, name string) (*v1beta1.MonitoringDashboard, error)
+	GetDashboards(namespace string) ([]v1beta1.MonitoringDashboard, error)
 }
 
 // Client is the client struct for Kiali Monitoring API over Kubernetes
 // API to get MonitoringDashboards
 type Client struct {
 	ClientInterface
-	client *rest.RESTClient
+	client  *rest.RESTClient
+	version schema.GroupVersion
 }
 
-// NewClient creates a new client able to fetch Kiali Monitoring API.
+// NewClient creates a new client able to fetch Kiali Monitoring API, with the default configuration:
+// in-cluster configuration when available, else kubeconfig. See NewClientWithConfig.
 func NewClient() (*Client, error) {
-	config, err := rest.InClusterConfig()
+	return NewClientWithConfig(extconfig.KubernetesConfig{})
+}
+
+// NewClientWithConfig creates a new client able to fetch Kiali Monitoring API.
+// It uses the in-cluster configuration when available, else falls back to kubeconfig (see LoadRestConfig)
+// Dashboards are fetched as v1beta1 when the API server serves it, else as v1alpha1 and converted.
+func NewClientWithConfig(cfg extconfig.KubernetesConfig) (*Client, error) {
+	config, err := LoadRestConfig(cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	types := runtime.NewScheme()
-	schemeBuilder := runtime.NewSchemeBuilder(
-		func(scheme *runtime.Scheme) error {
-			return nil
-		})
-
-	err = schemeBuilder.AddToScheme(types)
+	types, err := newScheme()
 	if err != nil {
 		return nil, err
 	}
 
-	client, err := newClientForAPI(config, v1alpha1.GroupVersion, types)
+	client, err := newClientForAPI(config, v1beta1.GroupVersion, types)
 	if err != nil {
 		return nil, err
 	}
+	version := servedVersion(cfg, client)
+	if version != v1beta1.GroupVersion {
+		client, err = newClientForAPI(config, version, types)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return &Client{
-		client: client,
+		client:  client,
+		version: version,
 	}, err
 }
 
+// servedVersion asks the API server which MonitoringDashboard versions are served.
+// It falls back to v1alpha1, which is served both by older CRDs and by the current one.
+func servedVersion(cfg extconfig.KubernetesConfig, client *rest.RESTClient) schema.GroupVersion {
+	if version, ok := servedVersions.Load(cfg); ok {
+		return version.(schema.GroupVersion)
+	}
+	raw, err := client.Get().AbsPath("/apis", v1beta1.GroupVersion.Group).DoRaw()
+	if err != nil {
+		return v1alpha1.GroupVersion
+	}
+	group := meta_v1.APIGroup{}
+	if err = json.Unmarshal(raw, &group); err != nil {
+		return v1alpha1.GroupVersion
+	}
+	version := v1alpha1.GroupVersion
+	for _, served := range group.Versions {
+		if served.Version == v1beta1.GroupVersion.Version {
+			version = v1beta1.GroupVersion
+		}
+	}
+	servedVersions.Store(cfg, version)
+	return version
+}
+
+// LoadRestConfig builds the Kubernetes REST config.
+// When neither a kubeconfig path nor a context is explicitly set, in-cluster config is tried first.
+// Otherwise (or if not running in a cluster), the standard kubeconfig loading rules apply: KUBECONFIG env var, then ~/.kube/config.
+// An explicit path and/or context from config take precedence over these defaults.
+func LoadRestConfig(cfg extconfig.KubernetesConfig) (*rest.Config, error) {
+	if cfg.ConfigPath == "" && cfg.Context == "" {
+		if config, err := rest.InClusterConfig(); err == nil {
+			return config, nil
+		}
+	}
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if cfg.ConfigPath != "" {
+		loadingRules.ExplicitPath = cfg.ConfigPath
+	}
+	overrides := clientcmd.ConfigOverrides{CurrentContext: cfg.Context}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &overrides).ClientConfig()
+}
+
+func newScheme() (*runtime.Scheme, error) {
+	types := runtime.NewScheme()
+	schemeBuilder := runtime.NewSchemeBuilder(
+		func(scheme *runtime.Scheme) error {
+			// Known types are needed to decode watch events
+			// Note that the API server names the list kind after the resource kind, which differs from the Go type name
+			scheme.AddKnownTypes(v1alpha1.GroupVersion, &v1alpha1.MonitoringDashboard{})
+			scheme.AddKnownTypeWithName(v1alpha1.GroupVersion.WithKind("MonitoringDashboardList"), &v1alpha1.MonitoringDashboardsList{})
+			meta_v1.AddToGroupVersion(scheme, v1alpha1.GroupVersion)
+			scheme.AddKnownTypes(v1beta1.GroupVersion, &v1beta1.MonitoringDashboard{}, &v1beta1.MonitoringDashboardList{})
+			meta_v1.AddToGroupVersion(scheme, v1beta1.GroupVersion)
+			return nil
+		})
+	err := schemeBuilder.AddToScheme(types)
+	return types, err
+}
+
 func newClientForAPI(fromCfg *rest.Config, groupVersion schema.GroupVersion, scheme *runtime.Scheme) (*rest.RESTClient, error) {
-	cfg := rest.Config{
-		Host:    fromCfg.Host,
-		APIPath: "/apis",
-		ContentConfig: rest.ContentConfig{
-			GroupVersion:         &groupVersion,
-			NegotiatedSerializer: serializer.DirectCodecFactory{CodecFactory: serializer.NewCodecFactory(scheme)},
-			ContentType:          runtime.ContentTypeJSON,
-		},
-		BearerToken:     fromCfg.BearerToken,
-		TLSClientConfig: fromCfg.TLSClientConfig,
-		QPS:             fromCfg.QPS,
-		Burst:           fromCfg.Burst,
-	}
-	return rest.RESTClientFor(&cfg)
+	// Copy the whole config rather than cherry-picking fields, as kubeconfig may rely on client certificates, auth providers etc.
+	cfg := rest.CopyConfig(fromCfg)
+	cfg.APIPath = "/apis"
+	cfg.ContentConfig = rest.ContentConfig{
+		GroupVersion:         &groupVersion,
+		NegotiatedSerializer: serializer.DirectCodecFactory{CodecFactory: serializer.NewCodecFactory(scheme)},
+		ContentType:          runtime.ContentTypeJSON,
+	}
+	return rest.RESTClientFor(cfg)
+}
+
+// newDashboard returns an empty MonitoringDashboard of the given version, to decode API server responses into
+func newDashboard(version schema.GroupVersion) runtime.Object {
+	if version == v1alpha1.GroupVersion {
+		return &v1alpha1.MonitoringDashboard{}
+	}
+	return &v1beta1.MonitoringDashboard{}
+}
+
+// toV1beta1 converts a MonitoringDashboard of any version to a new v1beta1 object
+func toV1beta1(obj interface{}) (*v1beta1.MonitoringDashboard, error) {
+	switch dashboard := obj.(type) {
+	case *v1beta1.MonitoringDashboard:
+		return dashboard.DeepCopy(), nil
+	case *v1alpha1.MonitoringDashboard:
+		return v1beta1.ConvertFromV1alpha1(dashboard), nil
+	}
+	return nil, fmt.Errorf("unexpected MonitoringDashboard type: %T", obj)
 }
 
 // GetDashboard returns a MonitoringDashboard for the given name
-func (in *Client) GetDashboard(namespace, name string) (*v1alpha1.MonitoringDashboard, error) {
-	result := v1alpha1.MonitoringDashboard{}
-	err := in.client.Get().Namespace(namespace).Resource("monitoringdashboards").SubResource(name).Do().Into(&result)
+func (in *Client) GetDashboard(namespace, name string) (*v1beta1.MonitoringDashboard, error) {
+	result := newDashboard(in.version)
+	err := in.client.Get().Namespace(namespace).Resource(monitoringDashboardsResource).SubResource(name).Do().Into(result)
 	if err != nil {
 		return nil, err
 	}
-	return &result, err
+	return toV1beta1(result)
 }
 
 // GetDashboards returns all MonitoringDashboards from the given namespace
-func (in *Client) GetDashboards(namespace string) ([]v1alpha1.MonitoringDashboard, error) {
-	result := v1alpha1.MonitoringDashboardsList{}
-	err := in.client.Get().Namespace(namespace).Resource("monitoringdashboards").Do().Into(&result)
+func (in *Client) GetDashboards(namespace string) ([]v1beta1.MonitoringDashboard, error) {
+	if in.version == v1alpha1.GroupVersion {
+		result := v1alpha1.MonitoringDashboardsList{}
+		err := in.client.Get().Namespace(namespace).Resource(monitoringDashboardsResource).Do().Into(&result)
+		if err != nil {
+			return nil, err
+		}
+		dashboards := make([]v1beta1.MonitoringDashboard, len(result.Items))
+		for i := range result.Items {
+			dashboards[i] = *v1beta1.ConvertFromV1alpha1(&result.Items[i])
+		}
+		return dashboards, nil
+	}
+	result := v1beta1.MonitoringDashboardList{}
+	err := in.client.Get().Namespace(namespace).Resource(monitoringDashboardsResource).Do().Into(&result)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,101 @@
+package kubernetes
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+
+	"github.com/kiali/k-charted/config/extconfig"
+	"github.com/kiali/k-charted/kubernetes/v1alpha1"
+	"github.com/kiali/k-charted/kubernetes/v1beta1"
+)
+
+func TestNewClient(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"kind":"APIGroup","name":"monitoring.kiali.io","versions":[{"groupVersion":"monitoring.kiali.io/v1alpha1","version":"v1alpha1"},{"groupVersion":"monitoring.kiali.io/v1beta1","version":"v1beta1"}]}`))
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "kubeconfig")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	kubeconfig := filepath.Join(dir, "config")
+	content := fmt.Sprintf("apiVersion: v1\nkind: Config\nclusters:\n- name: c\n  cluster:\n    server: %s\ncontexts:\n- name: ctx\n  context:\n    cluster: c\ncurrent-context: ctx\n", server.URL)
+	assert.Nil(ioutil.WriteFile(kubeconfig, []byte(content), 0600))
+
+	client, err := NewClientWithConfig(extconfig.KubernetesConfig{ConfigPath: kubeconfig})
+	assert.Nil(err)
+	assert.Equal(v1beta1.GroupVersion, client.version)
+
+	// Default configuration: not running in a cluster, kubeconfig is read from KUBECONFIG
+	defer os.Setenv("KUBECONFIG", os.Getenv("KUBECONFIG"))
+	defer os.Setenv("KUBERNETES_SERVICE_HOST", os.Getenv("KUBERNETES_SERVICE_HOST"))
+	os.Setenv("KUBECONFIG", kubeconfig)
+	os.Unsetenv("KUBERNETES_SERVICE_HOST")
+	client, err = NewClient()
+	assert.Nil(err)
+	assert.Equal(v1beta1.GroupVersion, client.version)
+}
+
+func TestDecodeAPIServerList(t *testing.T) {
+	assert := assert.New(t)
+
+	scheme, err := newScheme()
+	assert.Nil(err)
+	codecs := serializer.DirectCodecFactory{CodecFactory: serializer.NewCodecFactory(scheme)}
+	info, _ := runtime.SerializerInfoForMediaType(codecs.SupportedMediaTypes(), runtime.ContentTypeJSON)
+	decoder := codecs.DecoderToVersion(info.Serializer, v1alpha1.GroupVersion)
+
+	// As returned by the API server
+	raw := `{"apiVersion":"monitoring.kiali.io/v1alpha1","kind":"MonitoringDashboardList","metadata":{"resourceVersion":"1"},"items":[{"apiVersion":"monitoring.kiali.io/v1alpha1","kind":"MonitoringDashboard","metadata":{"name":"go"},"spec":{"title":"Go Metrics"}}]}`
+	list := v1alpha1.MonitoringDashboardsList{}
+	_, _, err = decoder.Decode([]byte(raw), nil, &list)
+	assert.Nil(err)
+	assert.Len(list.Items, 1)
+	assert.Equal("Go Metrics", list.Items[0].Spec.Title)
+}
+
+func TestDecodeAPIServerV1beta1List(t *testing.T) {
+	assert := assert.New(t)
+
+	scheme, err := newScheme()
+	assert.Nil(err)
+	codecs := serializer.DirectCodecFactory{CodecFactory: serializer.NewCodecFactory(scheme)}
+	info, _ := runtime.SerializerInfoForMediaType(codecs.SupportedMediaTypes(), runtime.ContentTypeJSON)
+	decoder := codecs.DecoderToVersion(info.Serializer, v1beta1.GroupVersion)
+
+	raw := `{"apiVersion":"monitoring.kiali.io/v1beta1","kind":"MonitoringDashboardList","metadata":{"resourceVersion":"1"},"items":[{"apiVersion":"monitoring.kiali.io/v1beta1","kind":"MonitoringDashboard","metadata":{"name":"go"},"spec":{"title":"Go Metrics"}}]}`
+	list := v1beta1.MonitoringDashboardList{}
+	_, _, err = decoder.Decode([]byte(raw), nil, &list)
+	assert.Nil(err)
+	assert.Len(list.Items, 1)
+	assert.Equal("Go Metrics", list.Items[0].Spec.Title)
+}
+
+func TestToV1beta1(t *testing.T) {
+	assert := assert.New(t)
+
+	old := &v1alpha1.MonitoringDashboard{Spec: v1alpha1.MonitoringDashboardSpec{Title: "Old"}}
+	d, err := toV1beta1(old)
+	assert.Nil(err)
+	assert.Equal("Old", d.Spec.Title)
+
+	current := &v1beta1.MonitoringDashboard{Spec: v1beta1.MonitoringDashboardSpec{Title: "Current"}}
+	d, err = toV1beta1(current)
+	assert.Nil(err)
+	assert.Equal("Current", d.Spec.Title)
+	assert.False(d == current, "must return a copy")
+
+	_, err = toV1beta1("foo")
+	assert.NotNil(err)
+}
@@ -0,0 +1,259 @@
+package kubernetes
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+
+	"github.com/kiali/k-charted/config/extconfig"
+	"github.com/kiali/k-charted/kubernetes/v1beta1"
+)
+
+const defaultReloadInterval = 10 * time.Second
+
+var (
+	fileClients     = make(map[fileClientKey]*FileClient)
+	fileClientsLock sync.Mutex
+	yamlSeparator   = regexp.MustCompile(`(?m)^---\s*$`)
+)
+
+type fileClientKey struct {
+	cfg             extconfig.DashboardFilesConfig
+	globalNamespace string
+}
+
+// FileClient is a ClientInterface implementation that loads MonitoringDashboards from YAML or JSON files, for deployments without Kubernetes.
+// The expected layout is one sub-directory per namespace, e.g. <root>/my-namespace/my-dashboard.yaml,
+// plus a global directory which content is served for the global namespace.
+// Files are polled periodically and reloaded when a change is detected.
+type FileClient struct {
+	ClientInterface
+	root            string
+	globalDir       string
+	globalNamespace string
+	lock            sync.RWMutex
+	dashboards      map[string]map[string]v1beta1.MonitoringDashboard
+	fingerprint     string
+	stop            chan struct{}
+}
+
+// GetFileClient returns the FileClient shared for the given configuration, creating it and starting its reload loop on first call.
+// Dashboards found in the global directory are served for globalNamespace.
+func GetFileClient(cfg extconfig.DashboardFilesConfig, globalNamespace string) (*FileClient, error) {
+	fileClientsLock.Lock()
+	defer fileClientsLock.Unlock()
+	key := fileClientKey{cfg: cfg, globalNamespace: globalNamespace}
+	if client, ok := fileClients[key]; ok {
+		return client, nil
+	}
+	client, err := NewFileClient(cfg, globalNamespace)
+	if err != nil {
+		return nil, err
+	}
+	fileClients[key] = client
+	return client, nil
+}
+
+// NewFileClient creates a new FileClient, loads dashboards and starts the reload loop.
+func NewFileClient(cfg extconfig.DashboardFilesConfig, globalNamespace string) (*FileClient, error) {
+	info, err := os.Stat(cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("dashboards path %s is not a directory", cfg.Path)
+	}
+	client := &FileClient{
+		root:            cfg.Path,
+		globalDir:       cfg.GlobalDir,
+		globalNamespace: globalNamespace,
+		stop:            make(chan struct{}),
+	}
+	if _, err := client.reload(); err != nil {
+		return nil, err
+	}
+	interval := cfg.ReloadInterval
+	if interval == 0 {
+		interval = defaultReloadInterval
+	}
+	if interval > 0 {
+		go client.watch(interval)
+	}
+	return client, nil
+}
+
+// Stop stops watching files. The client must not be used afterwards.
+func (in *FileClient) Stop() {
+	fileClientsLock.Lock()
+	defer fileClientsLock.Unlock()
+	for key, client := range fileClients {
+		if client == in {
+			delete(fileClients, key)
+		}
+	}
+	close(in.stop)
+}
+
+func (in *FileClient) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-in.stop:
+			return
+		case <-ticker.C:
+			// Errors are ignored here: previously loaded dashboards are kept until files are fixed
+			_, _ = in.reload()
+		}
+	}
+}
+
+// reload re-reads all files if anything changed in the directory tree since last load. It returns true if dashboards were reloaded.
+func (in *FileClient) reload() (bool, error) {
+	files, fingerprint, err := in.listFiles()
+	if err != nil {
+		return false, err
+	}
+	in.lock.RLock()
+	unchanged := fingerprint == in.fingerprint
+	in.lock.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	dashboards := make(map[string]map[string]v1beta1.MonitoringDashboard)
+	for _, file := range files {
+		namespace := in.namespaceOf(file)
+		parsed, err := parseDashboardsFile(file)
+		if err != nil {
+			return false, err
+		}
+		if _, ok := dashboards[namespace]; !ok {
+			dashboards[namespace] = make(map[string]v1beta1.MonitoringDashboard)
+		}
+		for _, d := range parsed {
+			d.Namespace = namespace
+			dashboards[namespace][d.Name] = d
+		}
+	}
+
+	in.lock.Lock()
+	defer in.lock.Unlock()
+	in.dashboards = dashboards
+	in.fingerprint = fingerprint
+	return true, nil
+}
+
+// listFiles lists dashboard files in namespace directories, with a fingerprint of the whole tree used to detect changes.
+// Files from the global directory come first, so that a namespace directory named after the global namespace takes precedence.
+func (in *FileClient) listFiles() ([]string, string, error) {
+	dirs, err := ioutil.ReadDir(in.root)
+	if err != nil {
+		return nil, "", err
+	}
+	var files []string
+	var fingerprint strings.Builder
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		entries, err := ioutil.ReadDir(filepath.Join(in.root, dir.Name()))
+		if err != nil {
+			return nil, "", err
+		}
+		for _, entry := range entries {
+			ext := strings.ToLower(filepath.Ext(entry.Name()))
+			if entry.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".json") {
+				continue
+			}
+			path := filepath.Join(in.root, dir.Name(), entry.Name())
+			files = append(files, path)
+			fingerprint.WriteString(fmt.Sprintf("%s:%d:%d;", path, entry.Size(), entry.ModTime().UnixNano()))
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return in.isGlobal(files[i]) && !in.isGlobal(files[j])
+	})
+	return files, fingerprint.String(), nil
+}
+
+func (in *FileClient) isGlobal(file string) bool {
+	return in.globalDir != "" && filepath.Base(filepath.Dir(file)) == in.globalDir
+}
+
+func (in *FileClient) namespaceOf(file string) string {
+	if in.isGlobal(file) {
+		return in.globalNamespace
+	}
+	return filepath.Base(filepath.Dir(file))
+}
+
+// parseDashboardsFile reads one or several MonitoringDashboards from a file. YAML files may contain multiple documents.
+// When metadata.name is not set, the file name without extension is used.
+func parseDashboardsFile(file string) ([]v1beta1.MonitoringDashboard, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	dashboards, err := parseDashboards(string(content), strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)))
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse dashboard file %s: %v", file, err)
+	}
+	return dashboards, nil
+}
+
+// parseDashboards reads one or several MonitoringDashboards from YAML (possibly multi-documents) or JSON content.
+// Documents without apiVersion are read as v1alpha1 (see v1beta1.Decode).
+func parseDashboards(content, defaultName string) ([]v1beta1.MonitoringDashboard, error) {
+	var dashboards []v1beta1.MonitoringDashboard
+	for _, doc := range yamlSeparator.Split(content, -1) {
+		if strings.TrimSpace(doc) == "" {
+			continue
+		}
+		raw, err := yaml.YAMLToJSON([]byte(doc))
+		if err != nil {
+			return nil, err
+		}
+		dashboard, err := v1beta1.Decode(raw)
+		if err != nil {
+			return nil, err
+		}
+		if dashboard.Name == "" {
+			dashboard.Name = defaultName
+		}
+		dashboards = append(dashboards, *dashboard)
+	}
+	return dashboards, nil
+}
+
+// GetDashboard returns a MonitoringDashboard for the given name
+func (in *FileClient) GetDashboard(namespace, name string) (*v1beta1.MonitoringDashboard, error) {
+	in.lock.RLock()
+	defer in.lock.RUnlock()
+	if dashboard, ok := in.dashboards[namespace][name]; ok {
+		return dashboard.DeepCopy(), nil
+	}
+	return nil, errors.NewNotFound(schema.GroupResource{Group: v1beta1.GroupVersion.Group, Resource: monitoringDashboardsResource}, name)
+}
+
+// GetDashboards returns all MonitoringDashboards from the given namespace
+func (in *FileClient) GetDashboards(namespace string) ([]v1beta1.MonitoringDashboard, error) {
+	in.lock.RLock()
+	defer in.lock.RUnlock()
+	dashboards := make([]v1beta1.MonitoringDashboard, 0, len(in.dashboards[namespace]))
+	for _, d := range in.dashboards[namespace] {
+		dashboards = append(dashboards, *d.DeepCopy())
+	}
+	sort.Slice(dashboards, func(i, j int) bool { return dashboards[i].Name < dashboards[j].Name })
+	return dashboards, nil
+}
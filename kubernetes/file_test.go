@@ -0,0 +1,153 @@
+package kubernetes
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/kiali/k-charted/config/extconfig"
+	"github.com/kiali/k-charted/kubernetes/v1beta1"
+)
+
+const yamlDashboards = `apiVersion: monitoring.kiali.io/v1alpha1
+kind: MonitoringDashboard
+metadata:
+  name: go
+spec:
+  title: Go Metrics
+  items:
+  - chart:
+      name: "Goroutines"
+      dataType: "raw"
+      metrics:
+      - metricName: "go_goroutines"
+        displayName: "Goroutines"
+---
+metadata:
+  name: vertx
+spec:
+  title: Vert.x Metrics
+---
+apiVersion: monitoring.kiali.io/v1beta1
+kind: MonitoringDashboard
+metadata:
+  name: nodejs
+spec:
+  title: Node.js Metrics
+  items:
+  - chart:
+      name: "Event loop lag"
+      metrics:
+      - metricName: "nodejs_eventloop_lag_seconds"
+      query:
+        dataType: "raw"
+        aggregator: "avg"
+`
+
+const jsonDashboard = `{"spec": {"title": "Overridden Go Metrics"}}`
+
+func writeFile(t *testing.T, path, content string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileClient(t *testing.T) {
+	assert := assert.New(t)
+
+	root, err := ioutil.TempDir("", "dashboards")
+	assert.Nil(err)
+	defer os.RemoveAll(root)
+	writeFile(t, filepath.Join(root, "global", "runtimes.yaml"), yamlDashboards)
+	writeFile(t, filepath.Join(root, "my-namespace", "go.json"), jsonDashboard)
+
+	client, err := NewFileClient(extconfig.DashboardFilesConfig{Path: root, GlobalDir: "global", ReloadInterval: -1}, "istio-system")
+	assert.Nil(err)
+	defer client.Stop()
+
+	d, err := client.GetDashboard("istio-system", "go")
+	assert.Nil(err)
+	assert.Equal("Go Metrics", d.Spec.Title)
+	assert.Equal("istio-system", d.Namespace)
+	assert.Len(d.Spec.Items, 1)
+	assert.Equal("go_goroutines", d.Spec.Items[0].Chart.Metrics[0].MetricName)
+
+	d, err = client.GetDashboard("istio-system", "nodejs")
+	assert.Nil(err)
+	assert.Equal(v1beta1.AggregatorAvg, d.Spec.Items[0].Chart.Query.Aggregator)
+
+	// Name is inferred from file name
+	d, err = client.GetDashboard("my-namespace", "go")
+	assert.Nil(err)
+	assert.Equal("Overridden Go Metrics", d.Spec.Title)
+
+	_, err = client.GetDashboard("my-namespace", "vertx")
+	assert.True(errors.IsNotFound(err))
+
+	all, err := client.GetDashboards("istio-system")
+	assert.Nil(err)
+	assert.Len(all, 3)
+	assert.Equal("go", all[0].Name)
+	assert.Equal("nodejs", all[1].Name)
+	assert.Equal("vertx", all[2].Name)
+}
+
+func TestFileClientReload(t *testing.T) {
+	assert := assert.New(t)
+
+	root, err := ioutil.TempDir("", "dashboards")
+	assert.Nil(err)
+	defer os.RemoveAll(root)
+	writeFile(t, filepath.Join(root, "my-namespace", "go.json"), jsonDashboard)
+
+	client, err := NewFileClient(extconfig.DashboardFilesConfig{Path: root, ReloadInterval: -1}, "")
+	assert.Nil(err)
+	defer client.Stop()
+
+	reloaded, err := client.reload()
+	assert.Nil(err)
+	assert.False(reloaded)
+
+	writeFile(t, filepath.Join(root, "my-namespace", "other.json"), `{"spec": {"title": "Other"}}`)
+	reloaded, err = client.reload()
+	assert.Nil(err)
+	assert.True(reloaded)
+
+	all, _ := client.GetDashboards("my-namespace")
+	assert.Len(all, 2)
+
+	// Broken file keeps previous state
+	writeFile(t, filepath.Join(root, "my-namespace", "broken.yaml"), "spec: [")
+	reloaded, err = client.reload()
+	assert.NotNil(err)
+	assert.False(reloaded)
+	all, _ = client.GetDashboards("my-namespace")
+	assert.Len(all, 2)
+}
+
+func TestFileClientPolling(t *testing.T) {
+	assert := assert.New(t)
+
+	root, err := ioutil.TempDir("", "dashboards")
+	assert.Nil(err)
+	defer os.RemoveAll(root)
+	writeFile(t, filepath.Join(root, "my-namespace", "go.json"), jsonDashboard)
+
+	client, err := NewFileClient(extconfig.DashboardFilesConfig{Path: root, ReloadInterval: 10 * time.Millisecond}, "")
+	assert.Nil(err)
+	defer client.Stop()
+
+	assert.Nil(os.Remove(filepath.Join(root, "my-namespace", "go.json")))
+	assert.Eventually(func() bool {
+		_, err := client.GetDashboard("my-namespace", "go")
+		return errors.IsNotFound(err)
+	}, 5*time.Second, 10*time.Millisecond)
+}
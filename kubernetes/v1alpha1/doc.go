@@ -0,0 +1,4 @@
+// Package v1alpha1 holds the original MonitoringDashboard custom resource types, still served and converted to v1beta1.
+// The CRD manifest is generated from these types, see "make gencrd".
+// +groupName=monitoring.kiali.io
+package v1alpha1
@@ -20,78 +20,165 @@ var GroupVersion = schema.GroupVersion{
 	Version: "v1alpha1",
 }
 
+// MonitoringDashboard is the custom resource defining a dashboard, made of charts
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=monitoringdashboards,singular=monitoringdashboard,scope=Namespaced
+// +kubebuilder:printcolumn:name="Title",type=string,JSONPath=".spec.title"
+// +kubebuilder:printcolumn:name="Runtime",type=string,JSONPath=".spec.runtime"
+// +kubebuilder:printcolumn:name="Discover On",type=string,JSONPath=".spec.discoverOn"
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=".metadata.creationTimestamp"
 type MonitoringDashboard struct {
 	meta_v1.TypeMeta   `json:",inline"`
 	meta_v1.ObjectMeta `json:"metadata"`
 	Spec               MonitoringDashboardSpec `json:"spec"`
 }
 
+// MonitoringDashboardsList is a list of MonitoringDashboard
+// +kubebuilder:object:root=true
 type MonitoringDashboardsList struct {
 	meta_v1.TypeMeta `json:",inline"`
 	meta_v1.ListMeta `json:"metadata"`
 	Items            []MonitoringDashboard `json:"items"`
 }
 
+// MonitoringDashboardSpec describes the content of a dashboard
 type MonitoringDashboardSpec struct {
-	Title         string                            `json:"title"`
-	Runtime       string                            `json:"runtime"`
-	DiscoverOn    string                            `json:"discoverOn"`
-	Items         []MonitoringDashboardItem         `json:"items"`
+	// Title of the dashboard
+	Title string `json:"title"`
+	// Runtime name, used to group dashboards; e.g. "Go", "Node.js"
+	// +optional
+	Runtime string `json:"runtime"`
+	// Name of a metric which presence makes this dashboard discovered automatically
+	// +optional
+	DiscoverOn string `json:"discoverOn"`
+	// Items are the charts, or references to other dashboards/charts, displayed in this dashboard
+	// +optional
+	Items []MonitoringDashboardItem `json:"items"`
+	// Links to external dashboards, such as Grafana
+	// +optional
 	ExternalLinks []MonitoringDashboardExternalLink `json:"externalLinks"`
 }
 
+// MonitoringDashboardItem is either a chart or a reference to other charts
 type MonitoringDashboardItem struct {
 	// Items are exclusive: either Include or Chart must be set (if both are set, Chart will be ignored)
 	// Include is a reference to another dashboard and/or chart
 	// Ex: "microprofile-1.0" will include the whole dashboard named "microprofile-1.0" at this position
 	//		 "microprofile-1.0$Thread count" will include only the chart named "Thread count" from that dashboard at this position
-	Include string                   `json:"include"`
-	Chart   MonitoringDashboardChart `json:"chart"`
+	// +optional
+	Include string `json:"include"`
+	// Chart definition, ignored if Include is set
+	// +optional
+	Chart MonitoringDashboardChart `json:"chart"`
 }
 
+// MonitoringDashboardChart describes a chart and the metrics it displays
 type MonitoringDashboardChart struct {
-	Name             string                           `json:"name"`
-	Unit             string                           `json:"unit"`      // Stands for the base unit (regardless its scale in datasource)
-	UnitScale        float64                          `json:"unitScale"` // Stands for the scale of the values in datasource, related to the base unit provided. E.g. unit: "seconds" and unitScale: 0.001 means that values in datasource are actually in milliseconds.
-	Spans            int                              `json:"spans"`
-	StartCollapsed   bool                             `json:"startCollapsed"`
-	ChartType        *string                          `json:"chartType"`
-	Min              *int                             `json:"min"`
-	Max              *int                             `json:"max"`
-	MetricName       string                           `json:"metricName"` // Deprecated; use Metrics instead
-	Metrics          []MonitoringDashboardMetric      `json:"metrics"`
-	DataType         string                           `json:"dataType"`   // DataType is either "raw", "rate" or "histogram"
-	Aggregator       string                           `json:"aggregator"` // Aggregator can be set for raw data. Ex: "sum", "avg". See https://prometheus.io/docs/prometheus/latest/querying/operators/#aggregation-operators
-	Aggregations     []MonitoringDashboardAggregation `json:"aggregations"`
-	XAxis            *string                          `json:"xAxis"`            // "time" (default) or "series"
-	GroupLabels      []string                         `json:"groupLabels"`      // Prometheus label to be used for grouping; Similar to Aggregations, except this grouping will be always turned on
-	SortLabel        string                           `json:"sortLabel"`        // Prometheus label to be used for sorting
-	SortLabelParseAs string                           `json:"sortLabelParseAs"` // Set "int" if the SortLabel needs to be parsed and compared as an integer
-}
-
+	// Name of the chart, displayed as title
+	Name string `json:"name"`
+	// Stands for the base unit (regardless its scale in datasource)
+	// +optional
+	Unit string `json:"unit"`
+	// Stands for the scale of the values in datasource, related to the base unit provided. E.g. unit: "seconds" and unitScale: 0.001 means that values in datasource are actually in milliseconds.
+	// +optional
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:ExclusiveMinimum=true
+	UnitScale float64 `json:"unitScale"`
+	// Width of the chart, in a 12-columns grid
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=12
+	Spans int `json:"spans"`
+	// Set true to render the chart collapsed initially
+	// +optional
+	StartCollapsed bool `json:"startCollapsed"`
+	// Type of chart, "line" by default
+	// +optional
+	// +kubebuilder:validation:Enum=area;line;bar;scatter
+	ChartType *string `json:"chartType"`
+	// Minimum value of the Y axis
+	// +optional
+	Min *int `json:"min"`
+	// Maximum value of the Y axis
+	// +optional
+	Max *int `json:"max"`
+	// Deprecated; use Metrics instead
+	// +optional
+	MetricName string `json:"metricName"`
+	// Metrics displayed in this chart
+	// +optional
+	Metrics []MonitoringDashboardMetric `json:"metrics"`
+	// DataType is either "raw", "rate" or "histogram"
+	// +kubebuilder:validation:Enum=raw;rate;histogram
+	DataType string `json:"dataType"`
+	// Aggregator can be set for raw data. Ex: "sum", "avg". See https://prometheus.io/docs/prometheus/latest/querying/operators/#aggregation-operators
+	// +optional
+	Aggregator string `json:"aggregator"`
+	// Labels that can be used for aggregation, selected by users
+	// +optional
+	Aggregations []MonitoringDashboardAggregation `json:"aggregations"`
+	// "time" (default) or "series"
+	// +optional
+	// +kubebuilder:validation:Enum=time;series
+	XAxis *string `json:"xAxis"`
+	// Prometheus label to be used for grouping; Similar to Aggregations, except this grouping will be always turned on
+	// +optional
+	GroupLabels []string `json:"groupLabels"`
+	// Prometheus label to be used for sorting
+	// +optional
+	SortLabel string `json:"sortLabel"`
+	// Set "int" if the SortLabel needs to be parsed and compared as an integer
+	// +optional
+	// +kubebuilder:validation:Enum="";int
+	SortLabelParseAs string `json:"sortLabelParseAs"`
+}
+
+// MonitoringDashboardMetric references a Prometheus metric
 type MonitoringDashboardMetric struct {
-	MetricName  string `json:"metricName"`
+	// Name of the Prometheus metric
+	MetricName string `json:"metricName"`
+	// Name displayed in legend
+	// +optional
 	DisplayName string `json:"displayName"`
 }
 
+// MonitoringDashboardAggregation is a label that can be used to aggregate metrics
 type MonitoringDashboardAggregation struct {
-	Label           string `json:"label"`
-	DisplayName     string `json:"displayName"`
-	SingleSelection bool   `json:"singleSelection"`
+	// Prometheus label name
+	Label string `json:"label"`
+	// Name displayed in UI
+	// +optional
+	DisplayName string `json:"displayName"`
+	// Set true to allow only one value to be selected at a time
+	// +optional
+	SingleSelection bool `json:"singleSelection"`
 }
 
+// MonitoringDashboardExternalLink is a link to a dashboard in another tool
 type MonitoringDashboardExternalLink struct {
-	Type      string                                   `json:"type"`
-	Name      string                                   `json:"name"`
+	// Type of the external tool; only "grafana" is supported
+	// +kubebuilder:validation:Enum=grafana
+	Type string `json:"type"`
+	// Name (or search pattern) of the external dashboard
+	Name string `json:"name"`
+	// Variables passed to the external dashboard
+	// +optional
 	Variables MonitoringDashboardExternalLinkVariables `json:"variables"`
 }
 
+// MonitoringDashboardExternalLinkVariables maps variables of the external dashboard
 type MonitoringDashboardExternalLinkVariables struct {
+	// +optional
 	Namespace string `json:"namespace,omitempty"`
-	App       string `json:"app,omitempty"`
-	Service   string `json:"service,omitempty"`
-	Version   string `json:"version,omitempty"`
-	Workload  string `json:"workload,omitempty"`
+	// +optional
+	App string `json:"app,omitempty"`
+	// +optional
+	Service string `json:"service,omitempty"`
+	// +optional
+	Version string `json:"version,omitempty"`
+	// +optional
+	Workload string `json:"workload,omitempty"`
 }
 
 // GetMetrics provides consistent MonitoringDashboardMetric slice in a backward-compatible way, if deprecated field MetricName is used instead of Metrics in Spec.
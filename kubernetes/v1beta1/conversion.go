@@ -0,0 +1,318 @@
+package v1beta1
+
+import (
+	"encoding/json"
+	"fmt"
+
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kiali/k-charted/kubernetes/v1alpha1"
+)
+
+const kind = "MonitoringDashboard"
+
+// FieldsAnnotation is the annotation holding, on a v1alpha1 MonitoringDashboard, the v1beta1 fields that v1alpha1 can't
+// represent. It's written by ConvertToV1alpha1 and read back by ConvertFromV1alpha1, so that a round trip is lossless.
+const FieldsAnnotation = "monitoring.kiali.io/v1beta1-fields"
+
+var (
+	// v1alpha1DataTypes and v1alpha1ChartTypes are the values allowed by the v1alpha1 CRD; empty values are left to defaults
+	v1alpha1DataTypes  = map[DataType]bool{"": true, Raw: true, Rate: true, Histogram: true}
+	v1alpha1ChartTypes = map[ChartType]bool{"": true, ChartTypeArea: true, ChartTypeLine: true, ChartTypeBar: true, ChartTypeScatter: true}
+)
+
+// v1beta1Fields are the v1beta1 fields kept in FieldsAnnotation
+type v1beta1Fields struct {
+	Variables []MonitoringDashboardVariable `json:"variables,omitempty"`
+	// Charts are keyed by item index
+	Charts map[int]v1beta1ChartFields `json:"charts,omitempty"`
+}
+
+// v1beta1ChartFields are the chart fields kept in FieldsAnnotation. Name is only used to check that the annotation
+// still matches the chart it was written for.
+type v1beta1ChartFields struct {
+	Name string `json:"name"`
+	// DataType and ChartType are only set when v1alpha1 doesn't allow them
+	DataType      DataType                     `json:"dataType,omitempty"`
+	ChartType     ChartType                    `json:"chartType,omitempty"`
+	HistogramMode HistogramMode                `json:"histogramMode,omitempty"`
+	Timeout       string                       `json:"timeout,omitempty"`
+	Rounding      *MonitoringDashboardRounding `json:"rounding,omitempty"`
+	MaxDataPoints int                          `json:"maxDataPoints,omitempty"`
+	// Exprs are indexed like the chart metrics
+	Exprs []string `json:"exprs,omitempty"`
+}
+
+func (f *v1beta1ChartFields) isEmpty() bool {
+	return f.DataType == "" && f.ChartType == "" && f.HistogramMode == "" && f.Timeout == "" && f.Rounding == nil && f.MaxDataPoints == 0 && len(f.Exprs) == 0
+}
+
+// Decode reads a JSON MonitoringDashboard of any supported version, converting it to v1beta1 if needed.
+// A missing apiVersion is read as v1alpha1, for backward compatibility with dashboards written before v1beta1.
+func Decode(data []byte) (*MonitoringDashboard, error) {
+	var typeMeta meta_v1.TypeMeta
+	if err := json.Unmarshal(data, &typeMeta); err != nil {
+		return nil, err
+	}
+	switch typeMeta.APIVersion {
+	case "", v1alpha1.GroupVersion.String():
+		var dashboard v1alpha1.MonitoringDashboard
+		if err := json.Unmarshal(data, &dashboard); err != nil {
+			return nil, err
+		}
+		return ConvertFromV1alpha1(&dashboard), nil
+	case GroupVersion.String():
+		var dashboard MonitoringDashboard
+		if err := json.Unmarshal(data, &dashboard); err != nil {
+			return nil, err
+		}
+		return &dashboard, nil
+	}
+	return nil, fmt.Errorf("unsupported apiVersion %s for MonitoringDashboard", typeMeta.APIVersion)
+}
+
+// ConvertFromV1alpha1 converts a v1alpha1 MonitoringDashboard. The deprecated MetricName is turned into a single metric.
+// Fields previously saved in FieldsAnnotation by ConvertToV1alpha1 are restored, and the annotation is removed.
+// The returned object doesn't share any data with the input.
+func ConvertFromV1alpha1(in *v1alpha1.MonitoringDashboard) *MonitoringDashboard {
+	out := MonitoringDashboard{
+		TypeMeta: meta_v1.TypeMeta{APIVersion: GroupVersion.String(), Kind: kind},
+		Spec: MonitoringDashboardSpec{
+			Title:      in.Spec.Title,
+			Runtime:    in.Spec.Runtime,
+			DiscoverOn: in.Spec.DiscoverOn,
+		},
+	}
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	if in.Spec.Items != nil {
+		out.Spec.Items = make([]MonitoringDashboardItem, len(in.Spec.Items))
+		for i, item := range in.Spec.Items {
+			out.Spec.Items[i] = MonitoringDashboardItem{Include: item.Include}
+			if item.Include == "" {
+				chart := convertChartFromV1alpha1(&item.Chart)
+				out.Spec.Items[i].Chart = &chart
+			}
+		}
+	}
+	for _, link := range in.Spec.ExternalLinks {
+		out.Spec.ExternalLinks = append(out.Spec.ExternalLinks, MonitoringDashboardExternalLink{
+			Type:      ExternalLinkType(link.Type),
+			Name:      link.Name,
+			Variables: MonitoringDashboardExternalLinkVariables(link.Variables),
+		})
+	}
+	restoreV1beta1Fields(&out)
+	return &out
+}
+
+// restoreV1beta1Fields reads and removes FieldsAnnotation. An annotation that can't be read is dropped: the dashboard
+// is then converted as any other v1alpha1 dashboard.
+func restoreV1beta1Fields(out *MonitoringDashboard) {
+	data, ok := out.Annotations[FieldsAnnotation]
+	if !ok {
+		return
+	}
+	delete(out.Annotations, FieldsAnnotation)
+	if len(out.Annotations) == 0 {
+		out.Annotations = nil
+	}
+	var fields v1beta1Fields
+	if err := json.Unmarshal([]byte(data), &fields); err != nil {
+		return
+	}
+	out.Spec.Variables = fields.Variables
+	for i, chartFields := range fields.Charts {
+		if i < 0 || i >= len(out.Spec.Items) {
+			continue
+		}
+		chart := out.Spec.Items[i].Chart
+		if chart == nil || chart.Name != chartFields.Name {
+			// Items were changed since the annotation was written
+			continue
+		}
+		if chartFields.DataType != "" {
+			chart.Query.DataType = chartFields.DataType
+		}
+		if chartFields.ChartType != "" {
+			chart.ChartType = chartFields.ChartType
+		}
+		chart.Query.HistogramMode = chartFields.HistogramMode
+		chart.Timeout = chartFields.Timeout
+		chart.Rounding = chartFields.Rounding
+		chart.MaxDataPoints = chartFields.MaxDataPoints
+		for j, expr := range chartFields.Exprs {
+			if j < len(chart.Metrics) {
+				chart.Metrics[j].Expr = expr
+			}
+		}
+	}
+}
+
+func convertChartFromV1alpha1(in *v1alpha1.MonitoringDashboardChart) MonitoringDashboardChart {
+	out := MonitoringDashboardChart{
+		Name:           in.Name,
+		Unit:           in.Unit,
+		UnitScale:      in.UnitScale,
+		Spans:          in.Spans,
+		StartCollapsed: in.StartCollapsed,
+		Min:            in.Min,
+		Max:            in.Max,
+		Query: MonitoringDashboardQuery{
+			DataType:    DataType(in.DataType),
+			Aggregator:  Aggregator(in.Aggregator),
+			GroupLabels: in.GroupLabels,
+		},
+	}
+	if in.ChartType != nil {
+		out.ChartType = ChartType(*in.ChartType)
+	}
+	if in.XAxis != nil {
+		out.XAxis = XAxis(*in.XAxis)
+	}
+	if len(in.Metrics) > 0 || in.MetricName != "" {
+		for _, metric := range in.GetMetrics() {
+			out.Metrics = append(out.Metrics, MonitoringDashboardMetric{MetricName: metric.MetricName, DisplayName: metric.DisplayName})
+		}
+	}
+	for _, agg := range in.Aggregations {
+		out.Aggregations = append(out.Aggregations, MonitoringDashboardAggregation(agg))
+	}
+	if in.SortLabel != "" || in.SortLabelParseAs != "" {
+		out.Sort = &MonitoringDashboardSort{Label: in.SortLabel, ParseAs: SortParseAs(in.SortLabelParseAs)}
+	}
+	// Pointers and slices are still shared at this point
+	var copied MonitoringDashboardChart
+	out.DeepCopyInto(&copied)
+	return copied
+}
+
+// ConvertToV1alpha1 converts a MonitoringDashboard back to v1alpha1, e.g. for clients still using the older version.
+// Fields that don't exist in v1alpha1, such as variables or metrics expressions, are saved as JSON in FieldsAnnotation.
+// So are data types and chart types that v1alpha1 doesn't allow, which are replaced by the closest v1alpha1 value.
+// The returned object doesn't share any data with the input.
+func ConvertToV1alpha1(in *MonitoringDashboard) *v1alpha1.MonitoringDashboard {
+	out := v1alpha1.MonitoringDashboard{
+		TypeMeta: meta_v1.TypeMeta{APIVersion: v1alpha1.GroupVersion.String(), Kind: kind},
+		Spec: v1alpha1.MonitoringDashboardSpec{
+			Title:      in.Spec.Title,
+			Runtime:    in.Spec.Runtime,
+			DiscoverOn: in.Spec.DiscoverOn,
+		},
+	}
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	if in.Spec.Items != nil {
+		out.Spec.Items = make([]v1alpha1.MonitoringDashboardItem, len(in.Spec.Items))
+		for i, item := range in.Spec.Items {
+			out.Spec.Items[i] = v1alpha1.MonitoringDashboardItem{Include: item.Include}
+			if item.Include == "" && item.Chart != nil {
+				out.Spec.Items[i].Chart = convertChartToV1alpha1(item.Chart)
+			}
+		}
+	}
+	for _, link := range in.Spec.ExternalLinks {
+		out.Spec.ExternalLinks = append(out.Spec.ExternalLinks, v1alpha1.MonitoringDashboardExternalLink{
+			Type:      string(link.Type),
+			Name:      link.Name,
+			Variables: v1alpha1.MonitoringDashboardExternalLinkVariables(link.Variables),
+		})
+	}
+	saveV1beta1Fields(in, &out)
+	return &out
+}
+
+func saveV1beta1Fields(in *MonitoringDashboard, out *v1alpha1.MonitoringDashboard) {
+	if _, ok := out.Annotations[FieldsAnnotation]; ok {
+		delete(out.Annotations, FieldsAnnotation)
+		if len(out.Annotations) == 0 {
+			out.Annotations = nil
+		}
+	}
+	fields := v1beta1Fields{Variables: in.Spec.Variables}
+	for i, item := range in.Spec.Items {
+		if item.Include != "" || item.Chart == nil {
+			continue
+		}
+		chartFields := v1beta1ChartFields{
+			Name:          item.Chart.Name,
+			HistogramMode: item.Chart.Query.HistogramMode,
+			Timeout:       item.Chart.Timeout,
+			Rounding:      item.Chart.Rounding,
+			MaxDataPoints: item.Chart.MaxDataPoints,
+		}
+		if !v1alpha1DataTypes[item.Chart.Query.DataType] {
+			chartFields.DataType = item.Chart.Query.DataType
+		}
+		if !v1alpha1ChartTypes[item.Chart.ChartType] {
+			chartFields.ChartType = item.Chart.ChartType
+		}
+		for j, metric := range item.Chart.Metrics {
+			if metric.Expr != "" {
+				if chartFields.Exprs == nil {
+					chartFields.Exprs = make([]string, len(item.Chart.Metrics))
+				}
+				chartFields.Exprs[j] = metric.Expr
+			}
+		}
+		if !chartFields.isEmpty() {
+			if fields.Charts == nil {
+				fields.Charts = make(map[int]v1beta1ChartFields)
+			}
+			fields.Charts[i] = chartFields
+		}
+	}
+	if len(fields.Variables) == 0 && len(fields.Charts) == 0 {
+		return
+	}
+	// Cannot fail: only strings, numbers and booleans
+	data, _ := json.Marshal(fields)
+	if out.Annotations == nil {
+		out.Annotations = make(map[string]string)
+	}
+	out.Annotations[FieldsAnnotation] = string(data)
+}
+
+func convertChartToV1alpha1(from *MonitoringDashboardChart) v1alpha1.MonitoringDashboardChart {
+	// Work on a copy so that nothing is shared with the input
+	var in MonitoringDashboardChart
+	from.DeepCopyInto(&in)
+	out := v1alpha1.MonitoringDashboardChart{
+		Name:           in.Name,
+		Unit:           in.Unit,
+		UnitScale:      in.UnitScale,
+		Spans:          in.Spans,
+		StartCollapsed: in.StartCollapsed,
+		Min:            in.Min,
+		Max:            in.Max,
+		DataType:       string(in.Query.DataType),
+		Aggregator:     string(in.Query.Aggregator),
+		GroupLabels:    in.Query.GroupLabels,
+	}
+	if !v1alpha1DataTypes[in.Query.DataType] {
+		// Summaries and expressions are charted as they are returned by Prometheus
+		out.DataType = string(Raw)
+	}
+	// Other chart types, such as heatmap or stat, are left to the default
+	switch {
+	case in.ChartType == ChartTypeBarByLabel:
+		chartType := string(ChartTypeBar)
+		out.ChartType = &chartType
+	case in.ChartType != "" && v1alpha1ChartTypes[in.ChartType]:
+		chartType := string(in.ChartType)
+		out.ChartType = &chartType
+	}
+	if in.XAxis != "" {
+		xAxis := string(in.XAxis)
+		out.XAxis = &xAxis
+	}
+	for _, metric := range in.Metrics {
+		out.Metrics = append(out.Metrics, v1alpha1.MonitoringDashboardMetric{MetricName: metric.MetricName, DisplayName: metric.DisplayName})
+	}
+	for _, agg := range in.Aggregations {
+		out.Aggregations = append(out.Aggregations, v1alpha1.MonitoringDashboardAggregation(agg))
+	}
+	if in.Sort != nil {
+		out.SortLabel = in.Sort.Label
+		out.SortLabelParseAs = string(in.Sort.ParseAs)
+	}
+	return out
+}
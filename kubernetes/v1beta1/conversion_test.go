@@ -0,0 +1,232 @@
+package v1beta1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kiali/k-charted/kubernetes/v1alpha1"
+)
+
+func TestConvertFromV1alpha1(t *testing.T) {
+	assert := assert.New(t)
+
+	chartType := "bar"
+	min := 0
+	in := v1alpha1.MonitoringDashboard{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "d", Namespace: "ns"},
+		Spec: v1alpha1.MonitoringDashboardSpec{
+			Title: "Dashboard",
+			Items: []v1alpha1.MonitoringDashboardItem{
+				{Include: "other"},
+				{Chart: v1alpha1.MonitoringDashboardChart{
+					Name:             "Deprecated metric",
+					MetricName:       "my_metric",
+					DataType:         v1alpha1.Raw,
+					Aggregator:       "sum",
+					ChartType:        &chartType,
+					Min:              &min,
+					GroupLabels:      []string{"code"},
+					SortLabel:        "code",
+					SortLabelParseAs: "int",
+				}},
+				{Chart: v1alpha1.MonitoringDashboardChart{
+					Name:     "Metrics",
+					Metrics:  []v1alpha1.MonitoringDashboardMetric{{MetricName: "m1", DisplayName: "M1"}, {MetricName: "m2"}},
+					DataType: v1alpha1.Histogram,
+				}},
+			},
+			ExternalLinks: []v1alpha1.MonitoringDashboardExternalLink{{Type: "grafana", Name: "G"}},
+		},
+	}
+	out := ConvertFromV1alpha1(&in)
+
+	assert.Equal("monitoring.kiali.io/v1beta1", out.APIVersion)
+	assert.Equal("MonitoringDashboard", out.Kind)
+	assert.Equal("d", out.Name)
+	assert.Equal("ns", out.Namespace)
+	assert.Len(out.Spec.Items, 3)
+	assert.Equal("other", out.Spec.Items[0].Include)
+
+	chart := out.Spec.Items[1].Chart
+	assert.Equal([]MonitoringDashboardMetric{{MetricName: "my_metric", DisplayName: "Deprecated metric"}}, chart.Metrics)
+	assert.Equal(MonitoringDashboardQuery{DataType: Raw, Aggregator: AggregatorSum, GroupLabels: []string{"code"}}, chart.Query)
+	assert.Equal(ChartTypeBar, chart.ChartType)
+	assert.Equal(XAxis(""), chart.XAxis)
+	assert.Equal(&MonitoringDashboardSort{Label: "code", ParseAs: SortAsInt}, chart.Sort)
+	assert.Equal(0, *chart.Min)
+	assert.Nil(chart.Max)
+
+	chart = out.Spec.Items[2].Chart
+	assert.Len(chart.Metrics, 2)
+	assert.Equal(Histogram, chart.Query.DataType)
+	assert.Nil(chart.Sort)
+	assert.Equal(ExternalLinkGrafana, out.Spec.ExternalLinks[0].Type)
+
+	// Output must not share data with input
+	*in.Spec.Items[1].Chart.Min = 5
+	in.Spec.Items[1].Chart.GroupLabels[0] = "changed"
+	assert.Equal(0, *out.Spec.Items[1].Chart.Min)
+	assert.Equal("code", out.Spec.Items[1].Chart.Query.GroupLabels[0])
+}
+
+func TestConvertRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	max := 10
+	in := MonitoringDashboard{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "d"},
+		Spec: MonitoringDashboardSpec{
+			Title:      "Dashboard",
+			Runtime:    "Go",
+			DiscoverOn: "go_info",
+			Items: []MonitoringDashboardItem{
+				{Include: "other$chart"},
+				{Chart: &MonitoringDashboardChart{
+					Name:         "Chart",
+					Unit:         "seconds",
+					UnitScale:    0.001,
+					Spans:        6,
+					ChartType:    ChartTypeScatter,
+					Max:          &max,
+					Metrics:      []MonitoringDashboardMetric{{MetricName: "m", DisplayName: "M"}},
+					Query:        MonitoringDashboardQuery{DataType: Rate, GroupLabels: []string{"a", "b"}},
+					Aggregations: []MonitoringDashboardAggregation{{Label: "a", DisplayName: "A", SingleSelection: true}},
+					XAxis:        XAxisSeries,
+					Sort:         &MonitoringDashboardSort{Label: "a"},
+				}},
+			},
+			ExternalLinks: []MonitoringDashboardExternalLink{{Type: ExternalLinkGrafana, Name: "G", Variables: MonitoringDashboardExternalLinkVariables{App: "app"}}},
+		},
+	}
+	old := ConvertToV1alpha1(&in)
+	assert.Equal("monitoring.kiali.io/v1alpha1", old.APIVersion)
+	assert.Equal("scatter", *old.Spec.Items[1].Chart.ChartType)
+	assert.Equal("series", *old.Spec.Items[1].Chart.XAxis)
+	assert.Equal("a", old.Spec.Items[1].Chart.SortLabel)
+	assert.Empty(old.Spec.Items[1].Chart.MetricName)
+
+	assert.Empty(old.Annotations)
+
+	back := ConvertFromV1alpha1(old)
+	in.TypeMeta = back.TypeMeta
+	assert.Equal(in, *back)
+}
+
+func TestConvertRoundTripV1beta1Fields(t *testing.T) {
+	assert := assert.New(t)
+
+	in := MonitoringDashboard{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "d", Annotations: map[string]string{"owner": "me"}},
+		Spec: MonitoringDashboardSpec{
+			Title: "Dashboard",
+			Items: []MonitoringDashboardItem{
+				{Include: "other"},
+				{Chart: &MonitoringDashboardChart{
+					Name:          "Histogram",
+					Metrics:       []MonitoringDashboardMetric{{MetricName: "m"}},
+					Query:         MonitoringDashboardQuery{DataType: Histogram, HistogramMode: HistogramNative},
+					Timeout:       "5s",
+					Rounding:      &MonitoringDashboardRounding{Precision: "0.01", ClientSide: true},
+					MaxDataPoints: 100,
+				}},
+				{Chart: &MonitoringDashboardChart{
+					Name:    "Exprs",
+					Metrics: []MonitoringDashboardMetric{{MetricName: "m"}, {DisplayName: "Ratio", Expr: "sum(rate(a[$rateInterval]))"}},
+					Query:   MonitoringDashboardQuery{DataType: Raw},
+				}},
+			},
+			Variables: []MonitoringDashboardVariable{
+				{Name: "code", Type: VariableLabelValues, Label: "response_code", Metric: "m"},
+				{Name: "mode", Type: VariableCustom, Options: []string{"a", "b"}, Default: "a"},
+			},
+		},
+	}
+	old := ConvertToV1alpha1(&in)
+	assert.Equal("me", old.Annotations["owner"])
+	assert.Contains(old.Annotations[FieldsAnnotation], `"histogramMode":"native"`)
+	assert.Len(old.Spec.Items[2].Chart.Metrics, 2)
+	assert.Empty(old.Spec.Items[2].Chart.Metrics[1].MetricName)
+
+	back := ConvertFromV1alpha1(old)
+	in.TypeMeta = back.TypeMeta
+	assert.Equal(in, *back)
+	assert.NotContains(back.Annotations, FieldsAnnotation)
+	// The converted v1alpha1 object is left untouched
+	assert.Contains(old.Annotations, FieldsAnnotation)
+
+	// Items changed by a v1alpha1 client don't get fields restored from another chart
+	old.Spec.Items[1], old.Spec.Items[2] = old.Spec.Items[2], old.Spec.Items[1]
+	back = ConvertFromV1alpha1(old)
+	assert.Equal(HistogramMode(""), back.Spec.Items[2].Chart.Query.HistogramMode)
+	assert.Empty(back.Spec.Items[1].Chart.Metrics[1].Expr)
+	assert.Len(back.Spec.Variables, 2)
+
+	// An unreadable annotation is dropped
+	old.Annotations[FieldsAnnotation] = "{"
+	back = ConvertFromV1alpha1(old)
+	assert.Nil(back.Spec.Variables)
+	assert.Equal(map[string]string{"owner": "me"}, back.Annotations)
+}
+
+func TestConvertV1beta1OnlyTypes(t *testing.T) {
+	assert := assert.New(t)
+
+	chart := func(name string, dataType DataType, chartType ChartType) MonitoringDashboardItem {
+		return MonitoringDashboardItem{Chart: &MonitoringDashboardChart{
+			Name:      name,
+			Metrics:   []MonitoringDashboardMetric{{MetricName: "m"}},
+			Query:     MonitoringDashboardQuery{DataType: dataType},
+			ChartType: chartType,
+		}}
+	}
+	in := MonitoringDashboard{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "d"},
+		Spec: MonitoringDashboardSpec{
+			Title: "Dashboard",
+			Items: []MonitoringDashboardItem{
+				chart("Expr", Expr, ChartTypeLine),
+				chart("Heatmap", Histogram, ChartTypeHeatmap),
+				chart("Stat", Rate, ChartTypeStat),
+				chart("Bars", Raw, ChartTypeBarByLabel),
+				chart("Summary", Summary, ChartTypeGauge),
+			},
+		},
+	}
+	in.Spec.Items[0].Chart.Metrics = []MonitoringDashboardMetric{{Expr: "sum(m)"}}
+
+	// Values not allowed by the v1alpha1 CRD are replaced, and kept in the annotation only
+	old := ConvertToV1alpha1(&in)
+	for i, dataType := range []string{"raw", "histogram", "rate", "raw", "raw"} {
+		assert.Equal(dataType, old.Spec.Items[i].Chart.DataType)
+	}
+	assert.Equal("line", *old.Spec.Items[0].Chart.ChartType)
+	assert.Nil(old.Spec.Items[1].Chart.ChartType)
+	assert.Nil(old.Spec.Items[2].Chart.ChartType)
+	assert.Equal("bar", *old.Spec.Items[3].Chart.ChartType)
+	assert.Nil(old.Spec.Items[4].Chart.ChartType)
+	assert.Contains(old.Annotations[FieldsAnnotation], `"dataType":"summary","chartType":"gauge"`)
+
+	back := ConvertFromV1alpha1(old)
+	in.TypeMeta = back.TypeMeta
+	assert.Equal(in, *back)
+}
+
+func TestDecode(t *testing.T) {
+	assert := assert.New(t)
+
+	// No apiVersion: read as v1alpha1
+	d, err := Decode([]byte(`{"metadata":{"name":"d"},"spec":{"title":"D","items":[{"chart":{"name":"c","metricName":"m","dataType":"rate"}}]}}`))
+	assert.Nil(err)
+	assert.Equal("d", d.Name)
+	assert.Equal(Rate, d.Spec.Items[0].Chart.Query.DataType)
+	assert.Equal("m", d.Spec.Items[0].Chart.Metrics[0].MetricName)
+
+	d, err = Decode([]byte(`{"apiVersion":"monitoring.kiali.io/v1beta1","kind":"MonitoringDashboard","metadata":{"name":"d"},"spec":{"title":"D","items":[{"chart":{"name":"c","metrics":[{"metricName":"m"}],"query":{"dataType":"histogram"}}}]}}`))
+	assert.Nil(err)
+	assert.Equal(Histogram, d.Spec.Items[0].Chart.Query.DataType)
+
+	_, err = Decode([]byte(`{"apiVersion":"monitoring.kiali.io/v2","kind":"MonitoringDashboard"}`))
+	assert.NotNil(err)
+}
@@ -0,0 +1,118 @@
+package v1beta1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// TODO: auto-generate the following deepcopy methods!
+
+func (in *MonitoringDashboard) DeepCopyInto(out *MonitoringDashboard) {
+	*out = *in
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+func (in *MonitoringDashboard) DeepCopy() *MonitoringDashboard {
+	if in == nil {
+		return nil
+	}
+	out := new(MonitoringDashboard)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *MonitoringDashboard) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+func (in *MonitoringDashboardList) DeepCopyInto(out *MonitoringDashboardList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]MonitoringDashboard, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+func (in *MonitoringDashboardList) DeepCopy() *MonitoringDashboardList {
+	if in == nil {
+		return nil
+	}
+	out := new(MonitoringDashboardList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *MonitoringDashboardList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+func (in *MonitoringDashboardSpec) DeepCopyInto(out *MonitoringDashboardSpec) {
+	*out = *in
+	if in.Items != nil {
+		out.Items = make([]MonitoringDashboardItem, len(in.Items))
+		for i := range in.Items {
+			out.Items[i].Include = in.Items[i].Include
+			if in.Items[i].Chart != nil {
+				out.Items[i].Chart = new(MonitoringDashboardChart)
+				in.Items[i].Chart.DeepCopyInto(out.Items[i].Chart)
+			}
+		}
+	}
+	if in.ExternalLinks != nil {
+		out.ExternalLinks = make([]MonitoringDashboardExternalLink, len(in.ExternalLinks))
+		copy(out.ExternalLinks, in.ExternalLinks)
+	}
+	if in.Variables != nil {
+		out.Variables = make([]MonitoringDashboardVariable, len(in.Variables))
+		for i := range in.Variables {
+			out.Variables[i] = in.Variables[i]
+			if in.Variables[i].Options != nil {
+				out.Variables[i].Options = make([]string, len(in.Variables[i].Options))
+				copy(out.Variables[i].Options, in.Variables[i].Options)
+			}
+		}
+	}
+}
+
+func (in *MonitoringDashboardChart) DeepCopyInto(out *MonitoringDashboardChart) {
+	*out = *in
+	if in.Min != nil {
+		min := *in.Min
+		out.Min = &min
+	}
+	if in.Max != nil {
+		max := *in.Max
+		out.Max = &max
+	}
+	if in.Metrics != nil {
+		out.Metrics = make([]MonitoringDashboardMetric, len(in.Metrics))
+		copy(out.Metrics, in.Metrics)
+	}
+	if in.Query.GroupLabels != nil {
+		out.Query.GroupLabels = make([]string, len(in.Query.GroupLabels))
+		copy(out.Query.GroupLabels, in.Query.GroupLabels)
+	}
+	if in.Aggregations != nil {
+		out.Aggregations = make([]MonitoringDashboardAggregation, len(in.Aggregations))
+		copy(out.Aggregations, in.Aggregations)
+	}
+	if in.Rounding != nil {
+		rounding := *in.Rounding
+		out.Rounding = &rounding
+	}
+	if in.Sort != nil {
+		sort := *in.Sort
+		out.Sort = &sort
+	}
+}
@@ -0,0 +1,5 @@
+// Package v1beta1 holds the MonitoringDashboard custom resource types, in the version consumed by k-charted.
+// Objects of older versions are converted to this one (see ConvertFromV1alpha1).
+// The CRD manifest is generated from these types, see "make gencrd".
+// +groupName=monitoring.kiali.io
+package v1beta1
@@ -0,0 +1,313 @@
+package v1beta1
+
+import (
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var GroupVersion = schema.GroupVersion{
+	Group:   "monitoring.kiali.io",
+	Version: "v1beta1",
+}
+
+// DataType defines how metrics of a chart are queried
+// +kubebuilder:validation:Enum=raw;rate;histogram;summary;expr
+type DataType string
+
+const (
+	// Raw stands for metrics displayed as is, possibly aggregated
+	Raw DataType = "raw"
+	// Rate stands for counters displayed as rates
+	Rate DataType = "rate"
+	// Histogram stands for histograms displayed as quantiles and average
+	Histogram DataType = "histogram"
+	// Summary stands for summaries, with a "quantile" label, displayed as quantiles and average
+	Summary DataType = "summary"
+	// Expr stands for metrics defined by a PromQL expression template
+	Expr DataType = "expr"
+)
+
+// HistogramMode tells which kind of Prometheus histogram is queried for the histogram data type
+// +kubebuilder:validation:Enum=auto;classic;native
+type HistogramMode string
+
+const (
+	// HistogramAuto queries classic histograms, falling back to native histograms when the classic series are missing
+	HistogramAuto HistogramMode = "auto"
+	// HistogramClassic queries classic histograms, made of _bucket, _sum and _count series
+	HistogramClassic HistogramMode = "classic"
+	// HistogramNative queries native (sparse) histograms
+	HistogramNative HistogramMode = "native"
+)
+
+// Aggregator is a Prometheus aggregation operator, see https://prometheus.io/docs/prometheus/latest/querying/operators/#aggregation-operators
+// +kubebuilder:validation:Enum=sum;min;max;avg;stddev;stdvar;count
+type Aggregator string
+
+const (
+	AggregatorSum    Aggregator = "sum"
+	AggregatorMin    Aggregator = "min"
+	AggregatorMax    Aggregator = "max"
+	AggregatorAvg    Aggregator = "avg"
+	AggregatorStddev Aggregator = "stddev"
+	AggregatorStdvar Aggregator = "stdvar"
+	AggregatorCount  Aggregator = "count"
+)
+
+// ChartType is the kind of chart rendered
+// +kubebuilder:validation:Enum=area;line;bar;scatter;heatmap;stat;gauge;table;bar-by-label
+type ChartType string
+
+const (
+	ChartTypeArea    ChartType = "area"
+	ChartTypeLine    ChartType = "line"
+	ChartTypeBar     ChartType = "bar"
+	ChartTypeScatter ChartType = "scatter"
+	// ChartTypeHeatmap renders the buckets distribution of a classic histogram over time
+	ChartTypeHeatmap ChartType = "heatmap"
+	// ChartTypeStat renders a single value
+	ChartTypeStat ChartType = "stat"
+	// ChartTypeGauge renders a single value within the min/max bounds
+	ChartTypeGauge ChartType = "gauge"
+	// ChartTypeTable renders a value per series, with their labels
+	ChartTypeTable ChartType = "table"
+	// ChartTypeBarByLabel renders a bar per series, compared by label
+	ChartTypeBarByLabel ChartType = "bar-by-label"
+)
+
+// IsInstant tells whether a chart type renders current values, from instant queries
+func (t ChartType) IsInstant() bool {
+	return t == ChartTypeStat || t == ChartTypeGauge || t == ChartTypeTable || t == ChartTypeBarByLabel
+}
+
+// XAxis is what the X axis of a chart stands for
+// +kubebuilder:validation:Enum=time;series
+type XAxis string
+
+const (
+	XAxisTime   XAxis = "time"
+	XAxisSeries XAxis = "series"
+)
+
+// SortParseAs tells how label values are compared when sorting
+// +kubebuilder:validation:Enum=int
+type SortParseAs string
+
+const (
+	// SortAsInt parses label values as integers before comparing them
+	SortAsInt SortParseAs = "int"
+)
+
+// ExternalLinkType is the kind of tool an external link points to
+// +kubebuilder:validation:Enum=grafana
+type ExternalLinkType string
+
+const (
+	ExternalLinkGrafana ExternalLinkType = "grafana"
+)
+
+// VariableType is the kind of a dashboard variable, defining where its options come from
+// +kubebuilder:validation:Enum=constant;custom;label_values
+type VariableType string
+
+const (
+	// VariableConstant stands for a variable with a single, fixed value
+	VariableConstant VariableType = "constant"
+	// VariableCustom stands for a variable with a fixed list of options
+	VariableCustom VariableType = "custom"
+	// VariableLabelValues stands for a variable which options are the values of a Prometheus label
+	VariableLabelValues VariableType = "label_values"
+)
+
+// MonitoringDashboard is the custom resource defining a dashboard, made of charts
+// +kubebuilder:object:root=true
+// +kubebuilder:storageversion
+// +kubebuilder:resource:path=monitoringdashboards,singular=monitoringdashboard,scope=Namespaced
+// +kubebuilder:printcolumn:name="Title",type=string,JSONPath=".spec.title"
+// +kubebuilder:printcolumn:name="Runtime",type=string,JSONPath=".spec.runtime"
+// +kubebuilder:printcolumn:name="Discover On",type=string,JSONPath=".spec.discoverOn"
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=".metadata.creationTimestamp"
+type MonitoringDashboard struct {
+	meta_v1.TypeMeta   `json:",inline"`
+	meta_v1.ObjectMeta `json:"metadata"`
+	Spec               MonitoringDashboardSpec `json:"spec"`
+}
+
+// MonitoringDashboardList is a list of MonitoringDashboard
+// +kubebuilder:object:root=true
+type MonitoringDashboardList struct {
+	meta_v1.TypeMeta `json:",inline"`
+	meta_v1.ListMeta `json:"metadata"`
+	Items            []MonitoringDashboard `json:"items"`
+}
+
+// MonitoringDashboardSpec describes the content of a dashboard
+type MonitoringDashboardSpec struct {
+	// Title of the dashboard
+	Title string `json:"title"`
+	// Runtime name, used to group dashboards; e.g. "Go", "Node.js"
+	Runtime string `json:"runtime,omitempty"`
+	// Name of a metric which presence makes this dashboard discovered automatically
+	DiscoverOn string `json:"discoverOn,omitempty"`
+	// Items are the charts, or references to other dashboards/charts, displayed in this dashboard
+	Items []MonitoringDashboardItem `json:"items,omitempty"`
+	// Links to external dashboards, such as Grafana
+	ExternalLinks []MonitoringDashboardExternalLink `json:"externalLinks,omitempty"`
+	// Variables which values are substituted as $name or ${name} in titles, metric names and label filters
+	Variables []MonitoringDashboardVariable `json:"variables,omitempty"`
+}
+
+// MonitoringDashboardVariable is a dashboard templating variable, similar to Grafana template variables.
+// Variables are resolved in declaration order, so a variable can refer to the previous ones.
+type MonitoringDashboardVariable struct {
+	// Name of the variable, used as $name or ${name}
+	// +kubebuilder:validation:Pattern=^[a-zA-Z_][a-zA-Z0-9_]*$
+	Name string `json:"name"`
+	// Name displayed in UI
+	DisplayName string `json:"displayName,omitempty"`
+	// Type is either "constant", "custom" or "label_values"
+	Type VariableType `json:"type"`
+	// Value of a constant variable
+	Value string `json:"value,omitempty"`
+	// Options of a custom variable
+	Options []string `json:"options,omitempty"`
+	// Prometheus label which values are the options of a label_values variable
+	Label string `json:"label,omitempty"`
+	// Metric in which label values are looked up, for label_values variables; when empty, all metrics of the namespace are considered
+	Metric string `json:"metric,omitempty"`
+	// Option selected when none is requested; the first option is used when empty
+	Default string `json:"default,omitempty"`
+}
+
+// MonitoringDashboardItem is either a chart or a reference to other charts
+type MonitoringDashboardItem struct {
+	// Items are exclusive: either Include or Chart must be set (if both are set, Chart will be ignored)
+	// Include is a reference to another dashboard and/or chart
+	// Ex: "microprofile-1.0" will include the whole dashboard named "microprofile-1.0" at this position
+	//		 "microprofile-1.0$Thread count" will include only the chart named "Thread count" from that dashboard at this position
+	Include string `json:"include,omitempty"`
+	// Chart definition, ignored if Include is set
+	Chart *MonitoringDashboardChart `json:"chart,omitempty"`
+}
+
+// MonitoringDashboardChart describes a chart and the metrics it displays
+type MonitoringDashboardChart struct {
+	// Name of the chart, displayed as title
+	Name string `json:"name"`
+	// Stands for the base unit (regardless its scale in datasource)
+	Unit string `json:"unit,omitempty"`
+	// Stands for the scale of the values in datasource, related to the base unit provided. E.g. unit: "seconds" and unitScale: 0.001 means that values in datasource are actually in milliseconds.
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:ExclusiveMinimum=true
+	UnitScale float64 `json:"unitScale,omitempty"`
+	// Width of the chart, in a 12-columns grid
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=12
+	Spans int `json:"spans,omitempty"`
+	// Set true to render the chart collapsed initially
+	StartCollapsed bool `json:"startCollapsed,omitempty"`
+	// Type of chart, "line" by default. "heatmap" is only available for histogram data type.
+	// "stat", "gauge", "table" and "bar-by-label" charts show the values at the end of the time range
+	ChartType ChartType `json:"chartType,omitempty"`
+	// Minimum value of the Y axis
+	Min *int `json:"min,omitempty"`
+	// Maximum value of the Y axis
+	Max *int `json:"max,omitempty"`
+	// Metrics displayed in this chart
+	Metrics []MonitoringDashboardMetric `json:"metrics"`
+	// Query defines how metrics are fetched
+	Query MonitoringDashboardQuery `json:"query"`
+	// Labels that can be used for aggregation, selected by users
+	Aggregations []MonitoringDashboardAggregation `json:"aggregations,omitempty"`
+	// "time" (default) or "series"
+	XAxis XAxis `json:"xAxis,omitempty"`
+	// Sorting of the series
+	Sort *MonitoringDashboardSort `json:"sort,omitempty"`
+	// Time budget of the queries of this chart, as a duration such as "10s", overriding the default chart timeout
+	// +kubebuilder:validation:Pattern=^([0-9]+(\.[0-9]+)?(ms|s|m|h))+$
+	Timeout string `json:"timeout,omitempty"`
+	// Rounding of the values, 0.001 in queries by default
+	Rounding *MonitoringDashboardRounding `json:"rounding,omitempty"`
+	// Maximum number of points per series, which raises the step of queries over long time ranges.
+	// When a limit is also requested by the dashboard query, the lowest applies.
+	// +kubebuilder:validation:Minimum=0
+	MaxDataPoints int `json:"maxDataPoints,omitempty"`
+}
+
+// MonitoringDashboardQuery defines how the metrics of a chart are queried
+type MonitoringDashboardQuery struct {
+	// DataType is either "raw", "rate", "histogram", "summary" or "expr"
+	DataType DataType `json:"dataType"`
+	// Aggregator can be set for raw data. Ex: "sum", "avg"
+	Aggregator Aggregator `json:"aggregator,omitempty"`
+	// Prometheus labels to be used for grouping; Similar to Aggregations, except this grouping will be always turned on
+	GroupLabels []string `json:"groupLabels,omitempty"`
+	// HistogramMode can be set for histogram data type: "classic", "native" or "auto" (default)
+	HistogramMode HistogramMode `json:"histogramMode,omitempty"`
+}
+
+// MonitoringDashboardSort defines how series of a chart are sorted
+type MonitoringDashboardSort struct {
+	// Prometheus label to be used for sorting
+	Label string `json:"label"`
+	// Set "int" if the label needs to be parsed and compared as an integer
+	ParseAs SortParseAs `json:"parseAs,omitempty"`
+}
+
+// MonitoringDashboardRounding defines how the values of a chart are rounded.
+// Values are rounded only when significant, that is, when the rounded value is greater than the precision.
+type MonitoringDashboardRounding struct {
+	// Precision of the rounded values, e.g. "0.001", or "none" to disable rounding
+	// +kubebuilder:validation:Pattern=^(none|[0-9]*\.?[0-9]+([eE][-+]?[0-9]+)?)$
+	Precision string `json:"precision"`
+	// Set true to round values when converting query results rather than in queries, which makes queries cheaper
+	ClientSide bool `json:"clientSide,omitempty"`
+}
+
+// RoundingNone disables rounding, as a rounding precision
+const RoundingNone = "none"
+
+// MonitoringDashboardMetric references a Prometheus metric
+type MonitoringDashboardMetric struct {
+	// Name of the Prometheus metric, required unless the data type is "expr"
+	MetricName string `json:"metricName,omitempty"`
+	// Name displayed in legend
+	DisplayName string `json:"displayName,omitempty"`
+	// PromQL expression template, required for the "expr" data type. It can contain the following placeholders:
+	// $__labels for the label matchers of the query, with braces, e.g. {namespace="ns",app="foo"};
+	// $__matchers for the same matchers without braces, to be combined with other matchers;
+	// $__by for the grouping clause, e.g. " by (app)", empty when there's no grouping;
+	// $__rate_interval for the rate interval, e.g. 1m
+	Expr string `json:"expr,omitempty"`
+}
+
+// MonitoringDashboardAggregation is a label that can be used to aggregate metrics
+type MonitoringDashboardAggregation struct {
+	// Prometheus label name
+	Label string `json:"label"`
+	// Name displayed in UI
+	DisplayName string `json:"displayName,omitempty"`
+	// Set true to allow only one value to be selected at a time
+	SingleSelection bool `json:"singleSelection,omitempty"`
+}
+
+// MonitoringDashboardExternalLink is a link to a dashboard in another tool
+type MonitoringDashboardExternalLink struct {
+	// Type of the external tool; only "grafana" is supported
+	Type ExternalLinkType `json:"type"`
+	// Name (or search pattern) of the external dashboard
+	Name string `json:"name"`
+	// Variables passed to the external dashboard
+	// +optional
+	Variables MonitoringDashboardExternalLinkVariables `json:"variables"`
+}
+
+// MonitoringDashboardExternalLinkVariables maps variables of the external dashboard
+type MonitoringDashboardExternalLinkVariables struct {
+	Namespace string `json:"namespace,omitempty"`
+	App       string `json:"app,omitempty"`
+	Service   string `json:"service,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Workload  string `json:"workload,omitempty"`
+}
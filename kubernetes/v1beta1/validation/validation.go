@@ -0,0 +1,333 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/kiali/k-charted/kubernetes/v1beta1"
+	"github.com/kiali/k-charted/prometheus/promql"
+)
+
+var (
+	validDataTypes     = []string{string(v1beta1.Raw), string(v1beta1.Rate), string(v1beta1.Histogram), string(v1beta1.Summary), string(v1beta1.Expr)}
+	validXAxis         = []string{"", string(v1beta1.XAxisTime), string(v1beta1.XAxisSeries)}
+	validSortParseAs   = []string{"", string(v1beta1.SortAsInt)}
+	validHistoModes    = []string{"", string(v1beta1.HistogramAuto), string(v1beta1.HistogramClassic), string(v1beta1.HistogramNative)}
+	validVariableTypes = []string{string(v1beta1.VariableConstant), string(v1beta1.VariableCustom), string(v1beta1.VariableLabelValues)}
+	variableName       = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	variableRef        = regexp.MustCompile(`\$\{(\w+)\}|\$(\w+)`)
+	validAggregators   = []string{"", string(v1beta1.AggregatorSum), string(v1beta1.AggregatorMin), string(v1beta1.AggregatorMax), string(v1beta1.AggregatorAvg),
+		string(v1beta1.AggregatorStddev), string(v1beta1.AggregatorStdvar), string(v1beta1.AggregatorCount)}
+	validChartTypes = []string{"", string(v1beta1.ChartTypeArea), string(v1beta1.ChartTypeLine), string(v1beta1.ChartTypeBar), string(v1beta1.ChartTypeScatter), string(v1beta1.ChartTypeHeatmap),
+		string(v1beta1.ChartTypeStat), string(v1beta1.ChartTypeGauge), string(v1beta1.ChartTypeTable), string(v1beta1.ChartTypeBarByLabel)}
+)
+
+// DashboardLookup finds a dashboard by name, for checking references. It must return a Kubernetes NotFound error
+// (see k8s.io/apimachinery/pkg/api/errors) when not found: other errors are reported as internal errors.
+type DashboardLookup func(name string) (*v1beta1.MonitoringDashboard, error)
+
+// ValidateDashboard checks a MonitoringDashboard, including its references to other dashboards that are looked up
+// through the provided function. The returned errors point to the invalid fields.
+func ValidateDashboard(dashboard *v1beta1.MonitoringDashboard, lookup DashboardLookup) field.ErrorList {
+	specPath := field.NewPath("spec")
+	allErrs := field.ErrorList{}
+	if dashboard.Name == "" {
+		allErrs = append(allErrs, field.Required(field.NewPath("metadata", "name"), ""))
+	}
+	if strings.TrimSpace(dashboard.Spec.Title) == "" {
+		allErrs = append(allErrs, field.Required(specPath.Child("title"), ""))
+	}
+
+	// The dashboard under validation must take precedence over any stored version of it
+	selfLookup := func(name string) (*v1beta1.MonitoringDashboard, error) {
+		if name == dashboard.Name {
+			return dashboard, nil
+		}
+		return lookup(name)
+	}
+
+	itemsPath := specPath.Child("items")
+	for i, item := range dashboard.Spec.Items {
+		itemPath := itemsPath.Index(i)
+		if strings.TrimSpace(item.Include) != "" {
+			allErrs = append(allErrs, validateInclude(dashboard.Name, strings.TrimSpace(item.Include), itemPath.Child("include"), selfLookup)...)
+		} else if item.Chart == nil {
+			allErrs = append(allErrs, field.Required(itemPath.Child("chart"), "either include or chart must be set"))
+		} else {
+			allErrs = append(allErrs, validateChart(item.Chart, itemPath.Child("chart"))...)
+		}
+	}
+
+	allErrs = append(allErrs, validateVariables(dashboard.Spec.Variables, specPath.Child("variables"))...)
+
+	linksPath := specPath.Child("externalLinks")
+	for i, link := range dashboard.Spec.ExternalLinks {
+		if link.Type != v1beta1.ExternalLinkGrafana {
+			allErrs = append(allErrs, field.NotSupported(linksPath.Index(i).Child("type"), link.Type, []string{string(v1beta1.ExternalLinkGrafana)}))
+		}
+		if link.Name == "" {
+			allErrs = append(allErrs, field.Required(linksPath.Index(i).Child("name"), ""))
+		}
+	}
+	return allErrs
+}
+
+// ValidateRawDashboard decodes and checks a JSON MonitoringDashboard of any supported version (see ValidateDashboard).
+// It additionally rejects an explicit unitScale of 0, which would otherwise be silently replaced by the default 1.0.
+// When the input is a v1alpha1 object, the returned errors point to v1alpha1 fields.
+func ValidateRawDashboard(raw []byte, lookup DashboardLookup) (*v1beta1.MonitoringDashboard, field.ErrorList, error) {
+	dashboard, err := v1beta1.Decode(raw)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot decode MonitoringDashboard: %v", err)
+	}
+	allErrs := ValidateDashboard(dashboard, lookup)
+
+	// Decode items loosely, to know which fields are explicitly set
+	var loose struct {
+		APIVersion string `json:"apiVersion"`
+		Spec       struct {
+			Items []struct {
+				Chart map[string]interface{} `json:"chart"`
+			} `json:"items"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(raw, &loose); err == nil {
+		itemsPath := field.NewPath("spec", "items")
+		for i, item := range loose.Spec.Items {
+			if scale, ok := item.Chart["unitScale"]; ok && scale == 0.0 {
+				allErrs = append(allErrs, field.Invalid(itemsPath.Index(i).Child("chart", "unitScale"), scale, "must not be 0; omit it to use the default scale 1.0"))
+			}
+		}
+		if loose.APIVersion != v1beta1.GroupVersion.String() {
+			for _, err := range allErrs {
+				err.Field = toV1alpha1Path(err.Field)
+			}
+		}
+	}
+	return dashboard, allErrs, nil
+}
+
+// v1alpha1Paths maps v1beta1 chart fields to their v1alpha1 counterparts
+var v1alpha1Paths = strings.NewReplacer(
+	".chart.query.", ".chart.",
+	".chart.sort.label", ".chart.sortLabel",
+	".chart.sort.parseAs", ".chart.sortLabelParseAs",
+)
+
+func toV1alpha1Path(path string) string {
+	return v1alpha1Paths.Replace(path)
+}
+
+func validateChart(chart *v1beta1.MonitoringDashboardChart, path *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if strings.TrimSpace(chart.Name) == "" {
+		allErrs = append(allErrs, field.Required(path.Child("name"), ""))
+	}
+	allErrs = append(allErrs, validateQuery(&chart.Query, path.Child("query"))...)
+	if !contains(validChartTypes, string(chart.ChartType)) {
+		allErrs = append(allErrs, field.NotSupported(path.Child("chartType"), chart.ChartType, validChartTypes[1:]))
+	} else if chart.ChartType == v1beta1.ChartTypeHeatmap {
+		if chart.Query.DataType != v1beta1.Histogram {
+			allErrs = append(allErrs, field.Forbidden(path.Child("chartType"), "heatmap can only be used with histogram data type"))
+		} else if chart.Query.HistogramMode == v1beta1.HistogramNative {
+			allErrs = append(allErrs, field.Forbidden(path.Child("chartType"), "heatmap can only be used with classic histograms"))
+		}
+	}
+	if !contains(validXAxis, string(chart.XAxis)) {
+		allErrs = append(allErrs, field.NotSupported(path.Child("xAxis"), chart.XAxis, validXAxis[1:]))
+	}
+	if chart.Sort != nil {
+		if !contains(validSortParseAs, string(chart.Sort.ParseAs)) {
+			allErrs = append(allErrs, field.NotSupported(path.Child("sort", "parseAs"), chart.Sort.ParseAs, validSortParseAs[1:]))
+		}
+		if chart.Sort.Label == "" {
+			allErrs = append(allErrs, field.Required(path.Child("sort", "label"), ""))
+		}
+	}
+	if chart.UnitScale < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("unitScale"), chart.UnitScale, "must be positive"))
+	}
+	if chart.Spans < 0 || chart.Spans > 12 {
+		allErrs = append(allErrs, field.Invalid(path.Child("spans"), chart.Spans, "must be between 1 and 12, or 0 for the default width"))
+	}
+	if chart.Timeout != "" {
+		if timeout, err := time.ParseDuration(chart.Timeout); err != nil || timeout <= 0 {
+			allErrs = append(allErrs, field.Invalid(path.Child("timeout"), chart.Timeout, "must be a positive duration, e.g. 10s"))
+		}
+	}
+	if chart.Rounding != nil {
+		precisionPath := path.Child("rounding", "precision")
+		if chart.Rounding.Precision == "" {
+			allErrs = append(allErrs, field.Required(precisionPath, ""))
+		} else if chart.Rounding.Precision != v1beta1.RoundingNone {
+			if precision, err := strconv.ParseFloat(chart.Rounding.Precision, 64); err != nil || !(precision > 0) || math.IsInf(precision, 0) {
+				allErrs = append(allErrs, field.Invalid(precisionPath, chart.Rounding.Precision, `must be a positive number, e.g. 0.001, or "none"`))
+			}
+		}
+	}
+	if chart.MaxDataPoints < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("maxDataPoints"), chart.MaxDataPoints, "must be positive"))
+	}
+	if chart.Min != nil && chart.Max != nil && *chart.Min > *chart.Max {
+		allErrs = append(allErrs, field.Invalid(path.Child("min"), *chart.Min, "must not be greater than max"))
+	}
+	if len(chart.Metrics) == 0 {
+		allErrs = append(allErrs, field.Required(path.Child("metrics"), "at least one metric is required"))
+	}
+	for i, metric := range chart.Metrics {
+		metricPath := path.Child("metrics").Index(i)
+		if chart.Query.DataType == v1beta1.Expr {
+			if strings.TrimSpace(metric.Expr) == "" {
+				allErrs = append(allErrs, field.Required(metricPath.Child("expr"), "expr is required for the expr data type"))
+			} else if err := checkExpr(metric.Expr); err != nil {
+				allErrs = append(allErrs, field.Invalid(metricPath.Child("expr"), metric.Expr, err.Error()))
+			}
+		} else {
+			if metric.MetricName == "" {
+				allErrs = append(allErrs, field.Required(metricPath.Child("metricName"), ""))
+			}
+			if metric.Expr != "" {
+				allErrs = append(allErrs, field.Forbidden(metricPath.Child("expr"), "expr can only be set for the expr data type"))
+			}
+		}
+	}
+	for i, agg := range chart.Aggregations {
+		if agg.Label == "" {
+			allErrs = append(allErrs, field.Required(path.Child("aggregations").Index(i).Child("label"), ""))
+		}
+	}
+	return allErrs
+}
+
+func validateQuery(query *v1beta1.MonitoringDashboardQuery, path *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if !contains(validDataTypes, string(query.DataType)) {
+		allErrs = append(allErrs, field.NotSupported(path.Child("dataType"), query.DataType, validDataTypes))
+	}
+	if query.Aggregator != "" && query.DataType != v1beta1.Raw {
+		allErrs = append(allErrs, field.Forbidden(path.Child("aggregator"), "aggregator can only be set for raw data type"))
+	} else if !contains(validAggregators, string(query.Aggregator)) {
+		allErrs = append(allErrs, field.NotSupported(path.Child("aggregator"), query.Aggregator, validAggregators[1:]))
+	}
+	if query.HistogramMode != "" && query.DataType != v1beta1.Histogram {
+		allErrs = append(allErrs, field.Forbidden(path.Child("histogramMode"), "histogramMode can only be set for histogram data type"))
+	} else if !contains(validHistoModes, string(query.HistogramMode)) {
+		allErrs = append(allErrs, field.NotSupported(path.Child("histogramMode"), query.HistogramMode, validHistoModes[1:]))
+	}
+	return allErrs
+}
+
+func validateVariables(variables []v1beta1.MonitoringDashboardVariable, path *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	names := make(map[string]bool)
+	for i, variable := range variables {
+		varPath := path.Index(i)
+		if !variableName.MatchString(variable.Name) {
+			allErrs = append(allErrs, field.Invalid(varPath.Child("name"), variable.Name, "must start with a letter or underscore, followed by letters, digits or underscores"))
+		} else if strings.HasPrefix(variable.Name, "__") {
+			allErrs = append(allErrs, field.Invalid(varPath.Child("name"), variable.Name, "names starting with __ are reserved for expression placeholders"))
+		} else if names[variable.Name] {
+			allErrs = append(allErrs, field.Duplicate(varPath.Child("name"), variable.Name))
+		}
+		names[variable.Name] = true
+		switch variable.Type {
+		case v1beta1.VariableConstant:
+			if variable.Value == "" {
+				allErrs = append(allErrs, field.Required(varPath.Child("value"), "value is required for constant variables"))
+			}
+		case v1beta1.VariableCustom:
+			if len(variable.Options) == 0 {
+				allErrs = append(allErrs, field.Required(varPath.Child("options"), "options are required for custom variables"))
+			} else if variable.Default != "" && !contains(variable.Options, variable.Default) {
+				allErrs = append(allErrs, field.NotSupported(varPath.Child("default"), variable.Default, variable.Options))
+			}
+		case v1beta1.VariableLabelValues:
+			if variable.Label == "" {
+				allErrs = append(allErrs, field.Required(varPath.Child("label"), "label is required for label_values variables"))
+			}
+		default:
+			allErrs = append(allErrs, field.NotSupported(varPath.Child("type"), variable.Type, validVariableTypes))
+		}
+	}
+	return allErrs
+}
+
+// checkExpr checks the PromQL syntax of an expression template, with placeholders replaced by sample values.
+// Expressions referring to dashboard variables can only be checked once their values are known, at query time.
+func checkExpr(expr string) error {
+	expanded := promql.ExpandExpr(expr, `{namespace="default"}`, "app", "1m")
+	if variableRef.MatchString(expanded) {
+		return nil
+	}
+	_, err := promql.ParseExpr(expanded)
+	return err
+}
+
+// validateInclude checks that the reference (and, if any, the referenced chart) exists, and that there's no circular dependency
+func validateInclude(from, reference string, path *field.Path, lookup DashboardLookup) field.ErrorList {
+	parts := strings.Split(reference, "$")
+	included, err := lookup(parts[0])
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return field.ErrorList{field.NotFound(path, reference)}
+		}
+		// The included dashboard may exist, but couldn't be read
+		return field.ErrorList{field.InternalError(path, fmt.Errorf("cannot look up %s: %v", parts[0], err))}
+	}
+	if len(parts) > 1 {
+		found := false
+		for _, item := range included.Spec.Items {
+			if item.Include == "" && item.Chart != nil && item.Chart.Name == parts[1] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return field.ErrorList{field.NotFound(path, reference)}
+		}
+	}
+	if cycle := findCycle(parts[0], []string{from}, lookup); cycle != nil {
+		return field.ErrorList{field.Invalid(path, reference, "circular dependency detected: "+strings.Join(cycle, " -> "))}
+	}
+	return field.ErrorList{}
+}
+
+// findCycle walks includes from the given dashboard, returning the dependency chain if it loops back to any dashboard of the chain
+func findCycle(name string, chain []string, lookup DashboardLookup) []string {
+	chain = append(chain, name)
+	for _, visited := range chain[:len(chain)-1] {
+		if visited == name {
+			return chain
+		}
+	}
+	dashboard, err := lookup(name)
+	if err != nil {
+		// Missing dashboards are reported separately
+		return nil
+	}
+	for _, item := range dashboard.Spec.Items {
+		if ref := strings.TrimSpace(item.Include); ref != "" {
+			if cycle := findCycle(strings.Split(ref, "$")[0], chain, lookup); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,307 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/kiali/k-charted/kubernetes/v1beta1"
+)
+
+func fakeDashboard(name string, items ...v1beta1.MonitoringDashboardItem) *v1beta1.MonitoringDashboard {
+	return &v1beta1.MonitoringDashboard{
+		ObjectMeta: meta_v1.ObjectMeta{Name: name},
+		Spec: v1beta1.MonitoringDashboardSpec{
+			Title: "Dashboard " + name,
+			Items: items,
+		},
+	}
+}
+
+func fakeChartItem(name, dataType string) v1beta1.MonitoringDashboardItem {
+	return v1beta1.MonitoringDashboardItem{
+		Chart: &v1beta1.MonitoringDashboardChart{
+			Name:    name,
+			Query:   v1beta1.MonitoringDashboardQuery{DataType: v1beta1.DataType(dataType)},
+			Metrics: []v1beta1.MonitoringDashboardMetric{{MetricName: "my_metric", DisplayName: name}},
+		},
+	}
+}
+
+func lookupIn(dashboards ...*v1beta1.MonitoringDashboard) DashboardLookup {
+	return func(name string) (*v1beta1.MonitoringDashboard, error) {
+		for _, d := range dashboards {
+			if d.Name == name {
+				return d, nil
+			}
+		}
+		return nil, k8serrors.NewNotFound(schema.GroupResource{Group: "monitoring.kiali.io", Resource: "monitoringdashboards"}, name)
+	}
+}
+
+func TestValidDashboard(t *testing.T) {
+	other := fakeDashboard("other", fakeChartItem("Other chart", "raw"))
+	d := fakeDashboard("d", fakeChartItem("My chart", "rate"), v1beta1.MonitoringDashboardItem{Include: "other$Other chart"})
+
+	errs := ValidateDashboard(d, lookupIn(other))
+	assert.Empty(t, errs)
+}
+
+func TestInvalidCharts(t *testing.T) {
+	assert := assert.New(t)
+
+	badType := fakeChartItem("Bad type", "rates")
+	badSort := fakeChartItem("Bad sort", "raw")
+	badSort.Chart.Sort = &v1beta1.MonitoringDashboardSort{Label: "code", ParseAs: "float"}
+	badXAxis := fakeChartItem("Bad axis", "raw")
+	badXAxis.Chart.XAxis = "foo"
+	noMetric := fakeChartItem("No metric", "raw")
+	noMetric.Chart.Metrics = nil
+
+	d := fakeDashboard("d", badType, badSort, badXAxis, noMetric)
+	errs := ValidateDashboard(d, lookupIn())
+
+	assert.Len(errs, 4)
+	assert.Equal("spec.items[0].chart.query.dataType", errs[0].Field)
+	assert.Equal("spec.items[1].chart.sort.parseAs", errs[1].Field)
+	assert.Equal("spec.items[2].chart.xAxis", errs[2].Field)
+	assert.Equal("spec.items[3].chart.metrics", errs[3].Field)
+}
+
+func TestMissingInclude(t *testing.T) {
+	assert := assert.New(t)
+
+	other := fakeDashboard("other", fakeChartItem("Other chart", "raw"))
+	d := fakeDashboard("d", v1beta1.MonitoringDashboardItem{Include: "missing"}, v1beta1.MonitoringDashboardItem{Include: "other$Missing chart"})
+
+	errs := ValidateDashboard(d, lookupIn(other))
+	assert.Len(errs, 2)
+	assert.Equal(field.ErrorTypeNotFound, errs[0].Type)
+	assert.Equal("spec.items[0].include", errs[0].Field)
+	assert.Equal("spec.items[1].include", errs[1].Field)
+}
+
+func TestIncludeLookupError(t *testing.T) {
+	assert := assert.New(t)
+
+	d := fakeDashboard("d", v1beta1.MonitoringDashboardItem{Include: "other"})
+	errs := ValidateDashboard(d, func(name string) (*v1beta1.MonitoringDashboard, error) {
+		return nil, errors.New("denied")
+	})
+
+	assert.Len(errs, 1)
+	assert.Equal(field.ErrorTypeInternal, errs[0].Type)
+	assert.Equal("spec.items[0].include", errs[0].Field)
+	assert.Contains(errs[0].Detail, "denied")
+}
+
+func TestCircularInclude(t *testing.T) {
+	assert := assert.New(t)
+
+	// The stored version of "d" doesn't include "other", but the new one does
+	storedD := fakeDashboard("d", fakeChartItem("My chart", "raw"))
+	other := fakeDashboard("other", v1beta1.MonitoringDashboardItem{Include: "d"})
+	d := fakeDashboard("d", v1beta1.MonitoringDashboardItem{Include: "other"})
+
+	errs := ValidateDashboard(d, lookupIn(storedD, other))
+	assert.Len(errs, 1)
+	assert.Equal("spec.items[0].include", errs[0].Field)
+	assert.Contains(errs[0].Detail, "d -> other -> d")
+}
+
+func TestExplicitZeroUnitScale(t *testing.T) {
+	assert := assert.New(t)
+
+	raw := `{"metadata":{"name":"d"},"spec":{"title":"D","items":[
+		{"chart":{"name":"c1","dataType":"raw","metrics":[{"metricName":"m"}]}},
+		{"chart":{"name":"c2","dataType":"raw","unitScale":0,"metrics":[{"metricName":"m"}]}}
+	]}}`
+
+	d, errs, err := ValidateRawDashboard([]byte(raw), lookupIn())
+	assert.Nil(err)
+	assert.Equal("d", d.Name)
+	assert.Len(errs, 1)
+	assert.Equal("spec.items[1].chart.unitScale", errs[0].Field)
+
+	_, _, err = ValidateRawDashboard([]byte("{"), lookupIn())
+	assert.NotNil(err)
+}
+
+func TestRawDashboardFieldPaths(t *testing.T) {
+	assert := assert.New(t)
+
+	// Errors must point to the fields of the version that was submitted
+	v1alpha1Raw := `{"apiVersion":"monitoring.kiali.io/v1alpha1","metadata":{"name":"d"},"spec":{"title":"D","items":[
+		{"chart":{"name":"c1","dataType":"foo","metricName":"m","sortLabelParseAs":"int"}}
+	]}}`
+	_, errs, err := ValidateRawDashboard([]byte(v1alpha1Raw), lookupIn())
+	assert.Nil(err)
+	assert.Len(errs, 2)
+	assert.Equal("spec.items[0].chart.dataType", errs[0].Field)
+	assert.Equal("spec.items[0].chart.sortLabel", errs[1].Field)
+
+	v1beta1Raw := `{"apiVersion":"monitoring.kiali.io/v1beta1","metadata":{"name":"d"},"spec":{"title":"D","items":[
+		{"chart":{"name":"c1","query":{"dataType":"foo"},"metrics":[{"metricName":"m"}],"sort":{"parseAs":"int"}}}
+	]}}`
+	_, errs, err = ValidateRawDashboard([]byte(v1beta1Raw), lookupIn())
+	assert.Nil(err)
+	assert.Len(errs, 2)
+	assert.Equal("spec.items[0].chart.query.dataType", errs[0].Field)
+	assert.Equal("spec.items[0].chart.sort.label", errs[1].Field)
+}
+
+func TestInvalidVariables(t *testing.T) {
+	assert := assert.New(t)
+
+	d := fakeDashboard("d", fakeChartItem("My chart", "raw"))
+	d.Spec.Variables = []v1beta1.MonitoringDashboardVariable{
+		{Name: "version", Type: v1beta1.VariableLabelValues, Label: "version"},
+		{Name: "version", Type: v1beta1.VariableConstant, Value: "v1"},
+		{Name: "1st", Type: v1beta1.VariableConstant, Value: "v1"},
+		{Name: "env", Type: v1beta1.VariableCustom, Options: []string{"dev", "prod"}, Default: "test"},
+		{Name: "other", Type: "query"},
+	}
+	errs := ValidateDashboard(d, lookupIn())
+
+	assert.Len(errs, 4)
+	assert.Equal("spec.variables[1].name", errs[0].Field)
+	assert.Equal("spec.variables[2].name", errs[1].Field)
+	assert.Equal("spec.variables[3].default", errs[2].Field)
+	assert.Equal("spec.variables[4].type", errs[3].Field)
+}
+
+func TestExprCharts(t *testing.T) {
+	assert := assert.New(t)
+
+	valid := fakeChartItem("Valid", "expr")
+	valid.Chart.Metrics = []v1beta1.MonitoringDashboardMetric{{DisplayName: "Errors", Expr: "sum(rate(errors$__labels[$__rate_interval]))$__by"}}
+	noExpr := fakeChartItem("No expr", "expr")
+	exprNotAllowed := fakeChartItem("Rate with expr", "rate")
+	exprNotAllowed.Chart.Metrics[0].Expr = "up"
+	badSyntax := fakeChartItem("Bad syntax", "expr")
+	badSyntax.Chart.Metrics = []v1beta1.MonitoringDashboardMetric{{DisplayName: "Errors", Expr: "sum(rate(errors$__labels[$__rate_interval])"}}
+	// Can't be checked until the variable is resolved
+	withVariable := fakeChartItem("With variable", "expr")
+	withVariable.Chart.Metrics = []v1beta1.MonitoringDashboardMetric{{DisplayName: "Errors", Expr: "sum(rate(errors$__labels[$interval]))$__by"}}
+
+	d := fakeDashboard("d", valid, noExpr, exprNotAllowed, badSyntax, withVariable)
+	d.Spec.Variables = []v1beta1.MonitoringDashboardVariable{{Name: "__labels", Type: v1beta1.VariableConstant, Value: "x"}}
+	errs := ValidateDashboard(d, lookupIn())
+
+	assert.Len(errs, 4)
+	assert.Equal("spec.items[1].chart.metrics[0].expr", errs[0].Field)
+	assert.Equal("spec.items[2].chart.metrics[0].expr", errs[1].Field)
+	assert.Equal("spec.items[3].chart.metrics[0].expr", errs[2].Field)
+	assert.Contains(errs[2].Detail, "unexpected end of input")
+	assert.Equal("spec.variables[0].name", errs[3].Field)
+}
+
+func TestInvalidRounding(t *testing.T) {
+	assert := assert.New(t)
+
+	valid := fakeChartItem("Valid", "rate")
+	valid.Chart.Rounding = &v1beta1.MonitoringDashboardRounding{Precision: "0.0001", ClientSide: true}
+	none := fakeChartItem("None", "rate")
+	none.Chart.Rounding = &v1beta1.MonitoringDashboardRounding{Precision: "none"}
+	missing := fakeChartItem("Missing", "rate")
+	missing.Chart.Rounding = &v1beta1.MonitoringDashboardRounding{ClientSide: true}
+	zero := fakeChartItem("Zero", "rate")
+	zero.Chart.Rounding = &v1beta1.MonitoringDashboardRounding{Precision: "0"}
+	invalid := fakeChartItem("Invalid", "rate")
+	invalid.Chart.Rounding = &v1beta1.MonitoringDashboardRounding{Precision: "3 digits"}
+
+	errs := ValidateDashboard(fakeDashboard("d", valid, none, missing, zero, invalid), lookupIn())
+
+	assert.Len(errs, 3)
+	assert.Equal("spec.items[2].chart.rounding.precision", errs[0].Field)
+	assert.Equal(field.ErrorTypeRequired, errs[0].Type)
+	assert.Equal("spec.items[3].chart.rounding.precision", errs[1].Field)
+	assert.Equal("spec.items[4].chart.rounding.precision", errs[2].Field)
+}
+
+func TestInvalidTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	valid := fakeChartItem("Valid", "rate")
+	valid.Chart.Timeout = "1m30s"
+	invalid := fakeChartItem("Invalid", "rate")
+	invalid.Chart.Timeout = "10"
+	negative := fakeChartItem("Negative", "rate")
+	negative.Chart.Timeout = "-1s"
+
+	errs := ValidateDashboard(fakeDashboard("d", valid, invalid, negative), lookupIn())
+
+	assert.Len(errs, 2)
+	assert.Equal("spec.items[1].chart.timeout", errs[0].Field)
+	assert.Equal("spec.items[2].chart.timeout", errs[1].Field)
+}
+
+func TestInvalidMaxDataPoints(t *testing.T) {
+	assert := assert.New(t)
+
+	valid := fakeChartItem("Valid", "rate")
+	valid.Chart.MaxDataPoints = 200
+	negative := fakeChartItem("Negative", "rate")
+	negative.Chart.MaxDataPoints = -1
+
+	errs := ValidateDashboard(fakeDashboard("d", valid, negative), lookupIn())
+
+	assert.Len(errs, 1)
+	assert.Equal("spec.items[1].chart.maxDataPoints", errs[0].Field)
+}
+
+func TestInvalidSpans(t *testing.T) {
+	assert := assert.New(t)
+
+	defaultWidth := fakeChartItem("Default", "rate")
+	full := fakeChartItem("Full", "rate")
+	full.Chart.Spans = 12
+	tooWide := fakeChartItem("Too wide", "rate")
+	tooWide.Chart.Spans = 13
+
+	errs := ValidateDashboard(fakeDashboard("d", defaultWidth, full, tooWide), lookupIn())
+
+	assert.Len(errs, 1)
+	assert.Equal("spec.items[2].chart.spans", errs[0].Field)
+	assert.Contains(errs[0].Detail, "or 0 for the default width")
+}
+
+func TestInvalidHistogramMode(t *testing.T) {
+	assert := assert.New(t)
+
+	valid := fakeChartItem("Valid", "histogram")
+	valid.Chart.Query.HistogramMode = v1beta1.HistogramNative
+	unknown := fakeChartItem("Unknown", "histogram")
+	unknown.Chart.Query.HistogramMode = "sparse"
+	notHisto := fakeChartItem("Not histogram", "rate")
+	notHisto.Chart.Query.HistogramMode = v1beta1.HistogramClassic
+
+	errs := ValidateDashboard(fakeDashboard("d", valid, unknown, notHisto), lookupIn())
+
+	assert.Len(errs, 2)
+	assert.Equal("spec.items[1].chart.query.histogramMode", errs[0].Field)
+	assert.Equal("spec.items[2].chart.query.histogramMode", errs[1].Field)
+}
+
+func TestInvalidHeatmap(t *testing.T) {
+	assert := assert.New(t)
+
+	valid := fakeChartItem("Valid", "histogram")
+	valid.Chart.ChartType = v1beta1.ChartTypeHeatmap
+	notHisto := fakeChartItem("Not histogram", "rate")
+	notHisto.Chart.ChartType = v1beta1.ChartTypeHeatmap
+	native := fakeChartItem("Native", "histogram")
+	native.Chart.ChartType = v1beta1.ChartTypeHeatmap
+	native.Chart.Query.HistogramMode = v1beta1.HistogramNative
+
+	errs := ValidateDashboard(fakeDashboard("d", valid, notHisto, native), lookupIn())
+
+	assert.Len(errs, 2)
+	assert.Equal("spec.items[1].chart.chartType", errs[0].Field)
+	assert.Equal("spec.items[2].chart.chartType", errs[1].Field)
+}
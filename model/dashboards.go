@@ -2,12 +2,13 @@ package model
 
 import (
 	"fmt"
+	"math"
 	"sort"
 	"strconv"
 
 	pmod "github.com/prometheus/common/model"
 
-	"github.com/kiali/k-charted/kubernetes/v1alpha1"
+	"github.com/kiali/k-charted/kubernetes/v1beta1"
 	"github.com/kiali/k-charted/prometheus"
 )
 
@@ -19,9 +20,19 @@ const (
 // MonitoringDashboard is the model representing custom monitoring dashboard, transformed from MonitoringDashboard k8s resource
 type MonitoringDashboard struct {
 	Title         string         `json:"title"`
+	Source        string         `json:"source"` // Name of the source (layer) providing this dashboard, e.g. "namespace" or "global"
 	Charts        []Chart        `json:"charts"`
 	Aggregations  []Aggregation  `json:"aggregations"`
 	ExternalLinks []ExternalLink `json:"externalLinks"`
+	Variables     []Variable     `json:"variables"`
+}
+
+// Variable is a resolved dashboard variable, with its options and current value, for rendering selectors
+type Variable struct {
+	Name        string   `json:"name"`
+	DisplayName string   `json:"displayName"`
+	Options     []string `json:"options"`
+	Value       string   `json:"value"`
 }
 
 // Chart is the model representing a custom chart, transformed from charts in MonitoringDashboard k8s resource
@@ -34,8 +45,24 @@ type Chart struct {
 	Min            *int            `json:"min,omitempty"`
 	Max            *int            `json:"max,omitempty"`
 	Metrics        []*SampleStream `json:"metrics"`
+	Heatmaps       []*Heatmap      `json:"heatmaps,omitempty"`
 	XAxis          *string         `json:"xAxis"`
 	Error          string          `json:"error"`
+	// Vector holds the current values of instant charts, such as stat or table charts, rather than Metrics
+	Vector []*Sample `json:"vector,omitempty"`
+	// Queries are the PromQL queries sent to Prometheus for this chart, for debugging
+	Queries []string `json:"queries,omitempty"`
+}
+
+// Heatmap is a bucket×time matrix of a histogram, for heatmap charts. Values are per-bucket (not cumulative).
+type Heatmap struct {
+	LabelSet map[string]string `json:"labelSet"`
+	// Buckets are the upper bounds of the buckets, scaled to the chart unit, in ascending order, "+Inf" last
+	Buckets []string `json:"buckets"`
+	// Timestamps are the timestamps of the columns, in milliseconds
+	Timestamps []int64 `json:"timestamps"`
+	// Values holds the value of each bucket (row) at each timestamp (column)
+	Values [][]float64 `json:"values"`
 }
 
 type ConversionParams struct {
@@ -43,6 +70,10 @@ type ConversionParams struct {
 	SortLabel        string
 	SortLabelParseAs string
 	RemoveSortLabel  bool
+	// Precision of values rounded on conversion, as they would be in queries; zero means no rounding
+	Precision float64
+	// MaxDataPoints is the maximum number of points per series, beyond which series are downsampled; zero means no limit
+	MaxDataPoints int
 }
 
 // BuildLabelsMap initiates a labels map out of a given metric name and optionally histogram stat
@@ -57,7 +88,7 @@ func BuildLabelsMap(name, stat string) map[string]string {
 	return labels
 }
 
-func (chart *Chart) FillHistogram(ref v1alpha1.MonitoringDashboardMetric, from prometheus.Histogram, conversionParams ConversionParams) {
+func (chart *Chart) FillHistogram(ref v1beta1.MonitoringDashboardMetric, from prometheus.Histogram, conversionParams ConversionParams) {
 	// Extract and sort keys for consistent ordering
 	stats := []string{}
 	for k := range from {
@@ -66,37 +97,186 @@ func (chart *Chart) FillHistogram(ref v1alpha1.MonitoringDashboardMetric, from p
 	sort.Strings(stats)
 	for _, stat := range stats {
 		promMetric := from[stat]
+		chart.addQuery(promMetric.Query)
 		if promMetric.Err != nil {
 			chart.Error = fmt.Sprintf("error in metric %s/%s: %v", ref.MetricName, stat, promMetric.Err)
 			return
 		}
 		metric := ConvertMatrix(promMetric.Matrix, BuildLabelsMap(ref.DisplayName, stat), conversionParams)
 		chart.Metrics = append(chart.Metrics, metric...)
+		chart.Vector = append(chart.Vector, ConvertVector(promMetric.Vector, BuildLabelsMap(ref.DisplayName, stat), conversionParams)...)
 	}
 }
 
-func (chart *Chart) FillMetric(ref v1alpha1.MonitoringDashboardMetric, from prometheus.Metric, conversionParams ConversionParams) {
+func (chart *Chart) FillMetric(ref v1beta1.MonitoringDashboardMetric, from prometheus.Metric, conversionParams ConversionParams) {
+	chart.addQuery(from.Query)
 	if from.Err != nil {
-		chart.Error = fmt.Sprintf("error in metric %s: %v", ref.MetricName, from.Err)
+		chart.Error = fmt.Sprintf("error in metric %s: %v", metricRefName(ref), from.Err)
 		return
 	}
 	metric := ConvertMatrix(from.Matrix, BuildLabelsMap(ref.DisplayName, ""), conversionParams)
 	chart.Metrics = append(chart.Metrics, metric...)
+	chart.Vector = append(chart.Vector, ConvertVector(from.Vector, BuildLabelsMap(ref.DisplayName, ""), conversionParams)...)
 }
 
-func ConvertMatrix(from pmod.Matrix, initialLabels map[string]string, conversionParams ConversionParams) []*SampleStream {
-	series := make([]*SampleStream, len(from))
-	if len(conversionParams.SortLabel) > 0 {
-		sort.Slice(from, func(i, j int) bool {
-			first := from[i].Metric[pmod.LabelName(conversionParams.SortLabel)]
-			second := from[j].Metric[pmod.LabelName(conversionParams.SortLabel)]
+// FillHeatmap converts cumulative bucket series, labelled with "le", into heatmaps: one per set of other labels
+func (chart *Chart) FillHeatmap(ref v1beta1.MonitoringDashboardMetric, from prometheus.Metric, conversionParams ConversionParams) {
+	chart.addQuery(from.Query)
+	if from.Err != nil {
+		chart.Error = fmt.Sprintf("error in metric %s: %v", metricRefName(ref), from.Err)
+		return
+	}
+	chart.Heatmaps = append(chart.Heatmaps, ConvertHeatmaps(from.Matrix, BuildLabelsMap(ref.DisplayName, ""), conversionParams)...)
+}
+
+// addQuery records a query sent for the chart, once: the same query can be sent for several clusters
+func (chart *Chart) addQuery(query string) {
+	if query == "" {
+		return
+	}
+	for _, q := range chart.Queries {
+		if q == query {
+			return
+		}
+	}
+	chart.Queries = append(chart.Queries, query)
+}
+
+type heatmapBucket struct {
+	le     string
+	bound  float64
+	values map[pmod.Time]pmod.SampleValue
+}
+
+type heatmapGroup struct {
+	labels  pmod.Metric
+	sortBy  string
+	buckets []*heatmapBucket
+}
+
+// ConvertHeatmaps groups cumulative bucket series by labels other than "le" and converts each group into a heatmap
+func ConvertHeatmaps(from pmod.Matrix, initialLabels map[string]string, conversionParams ConversionParams) []*Heatmap {
+	sortLabel := pmod.LabelName(conversionParams.SortLabel)
+	groups := make(map[pmod.Fingerprint]*heatmapGroup)
+	for _, stream := range from {
+		le, ok := stream.Metric[pmod.BucketLabel]
+		if !ok {
+			continue
+		}
+		bound, err := strconv.ParseFloat(string(le), 64)
+		if err != nil {
+			continue
+		}
+		labels := stream.Metric.Clone()
+		delete(labels, pmod.BucketLabel)
+		fp := labels.Fingerprint()
+		group, ok := groups[fp]
+		if !ok {
+			group = &heatmapGroup{labels: labels, sortBy: string(labels[sortLabel])}
+			if conversionParams.RemoveSortLabel {
+				delete(labels, sortLabel)
+			}
+			groups[fp] = group
+		}
+		bucket := &heatmapBucket{le: string(le), bound: bound, values: make(map[pmod.Time]pmod.SampleValue, len(stream.Values))}
+		for _, v := range stream.Values {
+			bucket.values[v.Timestamp] = v.Value
+		}
+		group.buckets = append(group.buckets, bucket)
+	}
+
+	sorted := make([]*heatmapGroup, 0, len(groups))
+	for _, group := range groups {
+		sorted = append(sorted, group)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		first, second := sorted[i], sorted[j]
+		if first.sortBy != second.sortBy {
 			if conversionParams.SortLabelParseAs == "int" {
 				// Note: in case of parsing error, 0 will be returned and used for sorting; error silently ignored.
-				iFirst, _ := strconv.Atoi(string(first))
-				iSecond, _ := strconv.Atoi(string(second))
+				iFirst, _ := strconv.Atoi(first.sortBy)
+				iSecond, _ := strconv.Atoi(second.sortBy)
 				return iFirst < iSecond
 			}
-			return first < second
+			return first.sortBy < second.sortBy
+		}
+		return first.labels.String() < second.labels.String()
+	})
+
+	heatmaps := make([]*Heatmap, len(sorted))
+	for i, group := range sorted {
+		labelSet := make(map[string]string, len(group.labels)+len(initialLabels))
+		for k, v := range initialLabels {
+			labelSet[k] = v
+		}
+		for k, v := range group.labels {
+			labelSet[string(k)] = string(v)
+		}
+		heatmaps[i] = convertHeatmap(group.buckets, labelSet, conversionParams.Scale)
+	}
+	return heatmaps
+}
+
+// convertHeatmap subtracts each bucket from the next one. Buckets missing at a timestamp count for zero, as well as
+// buckets lower than the previous ones, which happens when buckets aren't scraped at once.
+// The scale applies to the bucket bounds, which are in the unit of the observations, while values are counts.
+func convertHeatmap(buckets []*heatmapBucket, labelSet map[string]string, scale float64) *Heatmap {
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].bound < buckets[j].bound })
+	times := make(map[pmod.Time]bool)
+	for _, bucket := range buckets {
+		for t := range bucket.values {
+			times[t] = true
+		}
+	}
+	heatmap := &Heatmap{
+		LabelSet:   labelSet,
+		Buckets:    make([]string, len(buckets)),
+		Timestamps: make([]int64, 0, len(times)),
+		Values:     make([][]float64, len(buckets)),
+	}
+	for t := range times {
+		heatmap.Timestamps = append(heatmap.Timestamps, int64(t))
+	}
+	sort.Slice(heatmap.Timestamps, func(i, j int) bool { return heatmap.Timestamps[i] < heatmap.Timestamps[j] })
+
+	previous := make([]pmod.SampleValue, len(heatmap.Timestamps))
+	for b, bucket := range buckets {
+		heatmap.Buckets[b] = scaleBucketBound(bucket, scale)
+		heatmap.Values[b] = make([]float64, len(heatmap.Timestamps))
+		for i, t := range heatmap.Timestamps {
+			if cumulative, ok := bucket.values[pmod.Time(t)]; ok && cumulative > previous[i] {
+				heatmap.Values[b][i] = float64(cumulative - previous[i])
+				previous[i] = cumulative
+			}
+		}
+	}
+	return heatmap
+}
+
+func scaleBucketBound(bucket *heatmapBucket, scale float64) string {
+	if scale == 0 || scale == 1 || math.IsInf(bucket.bound, 0) {
+		return bucket.le
+	}
+	// Trims floating point noise, e.g. 0.1 * 3
+	return strconv.FormatFloat(bucket.bound*scale, 'g', 12, 64)
+}
+
+// metricRefName identifies a metric in error messages, as expression-based metrics have no metric name
+func metricRefName(ref v1beta1.MonitoringDashboardMetric) string {
+	if ref.MetricName != "" {
+		return ref.MetricName
+	}
+	if ref.DisplayName != "" {
+		return ref.DisplayName
+	}
+	return ref.Expr
+}
+
+func ConvertMatrix(from pmod.Matrix, initialLabels map[string]string, conversionParams ConversionParams) []*SampleStream {
+	series := make([]*SampleStream, len(from))
+	if len(conversionParams.SortLabel) > 0 {
+		sort.Slice(from, func(i, j int) bool {
+			return conversionParams.lessBySortLabel(from[i].Metric, from[j].Metric)
 		})
 	}
 	for i, s := range from {
@@ -105,31 +285,73 @@ func ConvertMatrix(from pmod.Matrix, initialLabels map[string]string, conversion
 	return series
 }
 
+// ConvertVector converts the result of an instant query, with the same parameters as ConvertMatrix
+func ConvertVector(from pmod.Vector, initialLabels map[string]string, conversionParams ConversionParams) []*Sample {
+	samples := make([]*Sample, len(from))
+	if len(conversionParams.SortLabel) > 0 {
+		sort.Slice(from, func(i, j int) bool {
+			return conversionParams.lessBySortLabel(from[i].Metric, from[j].Metric)
+		})
+	}
+	for i, s := range from {
+		samples[i] = &Sample{
+			LabelSet: convertLabelSet(s.Metric, initialLabels, conversionParams),
+			Value:    convertSamplePair(&pmod.SamplePair{Timestamp: s.Timestamp, Value: s.Value}, conversionParams.Scale, conversionParams.Precision),
+		}
+	}
+	return samples
+}
+
+func (conversionParams ConversionParams) lessBySortLabel(first, second pmod.Metric) bool {
+	firstValue := first[pmod.LabelName(conversionParams.SortLabel)]
+	secondValue := second[pmod.LabelName(conversionParams.SortLabel)]
+	if conversionParams.SortLabelParseAs == "int" {
+		// Note: in case of parsing error, 0 will be returned and used for sorting; error silently ignored.
+		iFirst, _ := strconv.Atoi(string(firstValue))
+		iSecond, _ := strconv.Atoi(string(secondValue))
+		return iFirst < iSecond
+	}
+	return firstValue < secondValue
+}
+
 type SampleStream struct {
 	LabelSet map[string]string `json:"labelSet"`
 	Values   []SamplePair      `json:"values"`
 }
 
+// Sample is the value of a series at a single time, for instant charts
+type Sample struct {
+	LabelSet map[string]string `json:"labelSet"`
+	Value    SamplePair        `json:"value"`
+}
+
 func convertSampleStream(from *pmod.SampleStream, initialLabels map[string]string, conversionParams ConversionParams) *SampleStream {
-	labelSet := make(map[string]string, len(from.Metric)+len(initialLabels))
+	values := make([]SamplePair, len(from.Values))
+	for i, v := range from.Values {
+		values[i] = convertSamplePair(&v, conversionParams.Scale, conversionParams.Precision)
+	}
+	if conversionParams.MaxDataPoints > 0 {
+		values = downsampleLTTB(values, conversionParams.MaxDataPoints)
+	}
+	return &SampleStream{
+		LabelSet: convertLabelSet(from.Metric, initialLabels, conversionParams),
+		Values:   values,
+	}
+}
+
+func convertLabelSet(from pmod.Metric, initialLabels map[string]string, conversionParams ConversionParams) map[string]string {
+	labelSet := make(map[string]string, len(from)+len(initialLabels))
 	for k, v := range initialLabels {
 		labelSet[k] = v
 	}
-	for k, v := range from.Metric {
+	for k, v := range from {
 		if conversionParams.SortLabel == string(k) && conversionParams.RemoveSortLabel {
 			// Do not keep sort label
 			continue
 		}
 		labelSet[string(k)] = string(v)
 	}
-	values := make([]SamplePair, len(from.Values))
-	for i, v := range from.Values {
-		values[i] = convertSamplePair(&v, conversionParams.Scale)
-	}
-	return &SampleStream{
-		LabelSet: labelSet,
-		Values:   values,
-	}
+	return labelSet
 }
 
 type SamplePair struct {
@@ -145,26 +367,46 @@ func (s SamplePair) MarshalJSON() ([]byte, error) {
 	}.MarshalJSON()
 }
 
-func convertSamplePair(from *pmod.SamplePair, scale float64) SamplePair {
+func convertSamplePair(from *pmod.SamplePair, scale, precision float64) SamplePair {
 	return SamplePair{
 		Timestamp: int64(from.Timestamp),
-		Value:     scale * float64(from.Value),
+		Value:     scale * roundSignificant(float64(from.Value), precision),
+	}
+}
+
+// roundSignificant rounds a value only if the result is significant, that is, higher than the precision.
+// It matches the rounding performed in queries by the Prometheus client, using the same arithmetic as PromQL round().
+func roundSignificant(value, precision float64) float64 {
+	if precision <= 0 {
+		return value
 	}
+	inverse := 1.0 / precision
+	if rounded := math.Floor(value*inverse+0.5) / inverse; rounded > precision {
+		return rounded
+	}
+	return value
 }
 
 // ConvertChart converts a k8s chart (from MonitoringDashboard k8s resource) into this models chart
-func ConvertChart(from v1alpha1.MonitoringDashboardChart) Chart {
+func ConvertChart(from v1beta1.MonitoringDashboardChart) Chart {
 	return Chart{
 		Name:           from.Name,
 		Unit:           from.Unit,
 		Spans:          from.Spans,
 		StartCollapsed: from.StartCollapsed,
-		ChartType:      from.ChartType,
+		ChartType:      optionalString(string(from.ChartType)),
 		Min:            from.Min,
 		Max:            from.Max,
 		Metrics:        []*SampleStream{},
-		XAxis:          from.XAxis,
+		XAxis:          optionalString(string(from.XAxis)),
+	}
+}
+
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
 	}
+	return &value
 }
 
 // Aggregation is the model representing label's allowed aggregation, transformed from aggregation in MonitoringDashboard k8s resource
@@ -176,9 +418,12 @@ type Aggregation struct {
 
 // ConvertAggregations converts a k8s aggregations (from MonitoringDashboard k8s resource) into this models aggregations
 // Results are sorted by DisplayName
-func ConvertAggregations(from v1alpha1.MonitoringDashboardSpec) []Aggregation {
+func ConvertAggregations(from v1beta1.MonitoringDashboardSpec) []Aggregation {
 	uniqueAggs := make(map[string]Aggregation)
 	for _, item := range from.Items {
+		if item.Chart == nil {
+			continue
+		}
 		for _, agg := range item.Chart.Aggregations {
 			uniqueAggs[agg.DisplayName] = Aggregation{Label: agg.Label, DisplayName: agg.DisplayName, SingleSelection: agg.SingleSelection}
 		}
@@ -195,9 +440,9 @@ func ConvertAggregations(from v1alpha1.MonitoringDashboardSpec) []Aggregation {
 
 // ExternalLink provides links to external dashboards (e.g. to Grafana)
 type ExternalLink struct {
-	URL       string                                            `json:"url"`
-	Name      string                                            `json:"name"`
-	Variables v1alpha1.MonitoringDashboardExternalLinkVariables `json:"variables"`
+	URL       string                                           `json:"url"`
+	Name      string                                           `json:"name"`
+	Variables v1beta1.MonitoringDashboardExternalLinkVariables `json:"variables"`
 }
 
 // Runtime holds the runtime title and associated dashboard template(s)
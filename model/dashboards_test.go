@@ -10,7 +10,7 @@ import (
 	pmod "github.com/prometheus/common/model"
 	"github.com/stretchr/testify/assert"
 
-	"github.com/kiali/k-charted/kubernetes/v1alpha1"
+	"github.com/kiali/k-charted/kubernetes/v1beta1"
 	"github.com/kiali/k-charted/prometheus"
 	"github.com/kiali/k-charted/prometheus/mock"
 )
@@ -18,11 +18,11 @@ import (
 func TestConvertAggregations(t *testing.T) {
 	assert := assert.New(t)
 
-	dashboardSpec := v1alpha1.MonitoringDashboardSpec{
-		Items: []v1alpha1.MonitoringDashboardItem{
+	dashboardSpec := v1beta1.MonitoringDashboardSpec{
+		Items: []v1beta1.MonitoringDashboardItem{
 			{
-				Chart: v1alpha1.MonitoringDashboardChart{
-					Aggregations: []v1alpha1.MonitoringDashboardAggregation{
+				Chart: &v1beta1.MonitoringDashboardChart{
+					Aggregations: []v1beta1.MonitoringDashboardAggregation{
 						{
 							DisplayName: "Path",
 							Label:       "path",
@@ -35,8 +35,8 @@ func TestConvertAggregations(t *testing.T) {
 				},
 			},
 			{
-				Chart: v1alpha1.MonitoringDashboardChart{
-					Aggregations: []v1alpha1.MonitoringDashboardAggregation{
+				Chart: &v1beta1.MonitoringDashboardChart{
+					Aggregations: []v1beta1.MonitoringDashboardAggregation{
 						{
 							DisplayName: "Address",
 							Label:       "address",
@@ -100,7 +100,7 @@ func TestConvertMetric(t *testing.T) {
 	assert := assert.New(t)
 	metric := mock.FakeCounter(10)
 	chart := Chart{}
-	ref := v1alpha1.MonitoringDashboardMetric{MetricName: "foo", DisplayName: "Foo"}
+	ref := v1beta1.MonitoringDashboardMetric{MetricName: "foo", DisplayName: "Foo"}
 
 	// Make sure metric is never nil, but empty slice
 	chart.FillMetric(ref, metric, ConversionParams{Scale: 2.0})
@@ -117,7 +117,7 @@ func TestConvertEmptyMetric(t *testing.T) {
 	assert := assert.New(t)
 	var metric prometheus.Metric
 	chart := Chart{}
-	ref := v1alpha1.MonitoringDashboardMetric{MetricName: "foo", DisplayName: "Foo"}
+	ref := v1beta1.MonitoringDashboardMetric{MetricName: "foo", DisplayName: "Foo"}
 
 	chart.FillMetric(ref, metric, ConversionParams{Scale: 0.0})
 	assert.Empty(chart.Error)
@@ -130,11 +130,31 @@ func TestConvertEmptyMetric(t *testing.T) {
 	assert.Empty(chart.Metrics)
 }
 
+func TestFillQueries(t *testing.T) {
+	assert := assert.New(t)
+	chart := Chart{}
+	ref := v1beta1.MonitoringDashboardMetric{MetricName: "foo", DisplayName: "Foo"}
+
+	metric := mock.FakeCounter(10)
+	metric.Query = "sum(foo)"
+	chart.FillMetric(ref, metric, ConversionParams{Scale: 1.0})
+	// Same query, e.g. for another cluster
+	chart.FillMetric(ref, metric, ConversionParams{Scale: 1.0})
+	// Queries are kept on error
+	chart.FillMetric(ref, prometheus.Metric{Err: errors.New("Some error"), Query: "sum(bar)"}, ConversionParams{Scale: 1.0})
+	assert.Equal([]string{"sum(foo)", "sum(bar)"}, chart.Queries)
+	assert.Equal("error in metric foo: Some error", chart.Error)
+
+	bytes, err := json.Marshal(Chart{})
+	assert.Nil(err)
+	assert.NotContains(string(bytes), "queries")
+}
+
 func TestConvertHistogram(t *testing.T) {
 	assert := assert.New(t)
 	histo := mock.FakeHistogram(10, 15)
 	chart := Chart{}
-	ref := v1alpha1.MonitoringDashboardMetric{MetricName: "foo", DisplayName: "Foo"}
+	ref := v1beta1.MonitoringDashboardMetric{MetricName: "foo", DisplayName: "Foo"}
 
 	chart.FillHistogram(ref, histo, ConversionParams{Scale: 2.0})
 	assert.Empty(chart.Error)
@@ -161,7 +181,7 @@ func TestConvertEmptyHistogram(t *testing.T) {
 	assert := assert.New(t)
 	var histo prometheus.Histogram
 	chart := Chart{}
-	ref := v1alpha1.MonitoringDashboardMetric{MetricName: "foo", DisplayName: "Foo"}
+	ref := v1beta1.MonitoringDashboardMetric{MetricName: "foo", DisplayName: "Foo"}
 
 	chart.FillHistogram(ref, histo, ConversionParams{Scale: 0.0})
 	assert.Empty(chart.Error)
@@ -195,7 +215,7 @@ func TestConvertMatrixNoSort(t *testing.T) {
 		},
 	}
 	chart := Chart{}
-	ref := v1alpha1.MonitoringDashboardMetric{MetricName: "foo", DisplayName: "Foo"}
+	ref := v1beta1.MonitoringDashboardMetric{MetricName: "foo", DisplayName: "Foo"}
 
 	chart.FillMetric(ref, metric, ConversionParams{Scale: 1.0})
 	assert.Empty(chart.Error)
@@ -214,6 +234,53 @@ func TestConvertMatrixNoSort(t *testing.T) {
 	assert.Equal(float64(3), chart.Metrics[2].Values[0].Value)
 }
 
+func TestConvertMatrixWithRounding(t *testing.T) {
+	assert := assert.New(t)
+	series := &model.SampleStream{
+		Metric: model.Metric{},
+		Values: []model.SamplePair{{Timestamp: 0, Value: 1.23456}, {Timestamp: 1, Value: 0.00042}, {Timestamp: 2, Value: 0.0056}},
+	}
+
+	// Rounded values that aren't significant are kept as is, as with rounding in queries
+	converted := ConvertMatrix(model.Matrix{series}, map[string]string{}, ConversionParams{Scale: 1000.0, Precision: 0.001})
+	assert.Len(converted, 1)
+	assert.InDelta(1235.0, converted[0].Values[0].Value, 1e-9)
+	assert.InDelta(0.42, converted[0].Values[1].Value, 1e-9)
+	assert.InDelta(6.0, converted[0].Values[2].Value, 1e-9)
+
+	converted = ConvertMatrix(model.Matrix{series}, map[string]string{}, ConversionParams{Scale: 1.0})
+	assert.Equal(1.23456, converted[0].Values[0].Value)
+	assert.Equal(0.00042, converted[0].Values[1].Value)
+}
+
+func TestConvertVector(t *testing.T) {
+	assert := assert.New(t)
+	vector := model.Vector{
+		{Metric: model.Metric{"key": "10"}, Value: 3, Timestamp: 1000},
+		{Metric: model.Metric{"key": "9"}, Value: 2, Timestamp: 1000},
+	}
+	chart := Chart{}
+	ref := v1beta1.MonitoringDashboardMetric{MetricName: "foo", DisplayName: "Foo"}
+
+	chart.FillMetric(ref, prometheus.Metric{Vector: vector}, ConversionParams{Scale: 2.0, SortLabel: "key", SortLabelParseAs: "int"})
+	assert.Empty(chart.Error)
+	assert.Empty(chart.Metrics)
+	assert.Len(chart.Vector, 2)
+	assert.Equal(map[string]string{"__name__": "Foo", "key": "9"}, chart.Vector[0].LabelSet)
+	assert.Equal(float64(4), chart.Vector[0].Value.Value)
+	assert.Equal(int64(1000), chart.Vector[0].Value.Timestamp)
+	assert.Equal("10", chart.Vector[1].LabelSet["key"])
+
+	bytes, err := json.Marshal(chart.Vector[0])
+	assert.Nil(err)
+	assert.Equal(`{"labelSet":{"__name__":"Foo","key":"9"},"value":[1,"4"]}`, string(bytes))
+
+	// Range charts have no vector
+	bytes, err = json.Marshal(Chart{})
+	assert.Nil(err)
+	assert.NotContains(string(bytes), "vector")
+}
+
 func TestConvertMatrixWithLabelSort(t *testing.T) {
 	assert := assert.New(t)
 	metric := prometheus.Metric{
@@ -224,7 +291,7 @@ func TestConvertMatrixWithLabelSort(t *testing.T) {
 		},
 	}
 	chart := Chart{}
-	ref := v1alpha1.MonitoringDashboardMetric{MetricName: "foo", DisplayName: "Foo"}
+	ref := v1beta1.MonitoringDashboardMetric{MetricName: "foo", DisplayName: "Foo"}
 
 	chart.FillMetric(ref, metric, ConversionParams{Scale: 1.0, SortLabel: "key"})
 	assert.Empty(chart.Error)
@@ -253,7 +320,7 @@ func TestConvertMatrixWithLabelSortAndRemoveLabel(t *testing.T) {
 		},
 	}
 	chart := Chart{}
-	ref := v1alpha1.MonitoringDashboardMetric{MetricName: "foo", DisplayName: "Foo"}
+	ref := v1beta1.MonitoringDashboardMetric{MetricName: "foo", DisplayName: "Foo"}
 
 	chart.FillMetric(ref, metric, ConversionParams{Scale: 1.0, SortLabel: "key", RemoveSortLabel: true})
 	assert.Empty(chart.Error)
@@ -279,7 +346,7 @@ func TestConvertMatrixWithLabelSortLabelNotFound(t *testing.T) {
 		},
 	}
 	chart := Chart{}
-	ref := v1alpha1.MonitoringDashboardMetric{MetricName: "foo", DisplayName: "Foo"}
+	ref := v1beta1.MonitoringDashboardMetric{MetricName: "foo", DisplayName: "Foo"}
 
 	// Test again with not-found sort label
 	chart = Chart{}
@@ -311,7 +378,7 @@ func TestConvertMatrixWithLabelSortAsInt(t *testing.T) {
 		},
 	}
 	chart := Chart{}
-	ref := v1alpha1.MonitoringDashboardMetric{MetricName: "foo", DisplayName: "Foo"}
+	ref := v1beta1.MonitoringDashboardMetric{MetricName: "foo", DisplayName: "Foo"}
 
 	chart.FillMetric(ref, metric, ConversionParams{Scale: 1.0, SortLabel: "key", SortLabelParseAs: "int"})
 	assert.Empty(chart.Error)
@@ -329,3 +396,58 @@ func TestConvertMatrixWithLabelSortAsInt(t *testing.T) {
 	assert.Equal("10", chart.Metrics[2].LabelSet["key"])
 	assert.Equal(float64(2), chart.Metrics[2].Values[0].Value)
 }
+
+func TestConvertChart(t *testing.T) {
+	assert := assert.New(t)
+
+	chart := ConvertChart(v1beta1.MonitoringDashboardChart{Name: "c", ChartType: v1beta1.ChartTypeBar})
+	assert.Equal("c", chart.Name)
+	assert.Equal("bar", *chart.ChartType)
+	// Unset enums are left to the UI defaults
+	assert.Nil(chart.XAxis)
+	assert.NotNil(chart.Metrics)
+}
+
+func bucketStream(le, app string, values ...float64) *pmod.SampleStream {
+	stream := &pmod.SampleStream{Metric: pmod.Metric{"le": pmod.LabelValue(le), "app": pmod.LabelValue(app)}}
+	for i, v := range values {
+		stream.Values = append(stream.Values, pmod.SamplePair{Timestamp: pmod.Time(1000 * (i + 1)), Value: pmod.SampleValue(v)})
+	}
+	return stream
+}
+
+func TestFillHeatmap(t *testing.T) {
+	assert := assert.New(t)
+
+	matrix := pmod.Matrix{
+		bucketStream("+Inf", "foo", 10, 12),
+		bucketStream("0.1", "foo", 2, 3),
+		bucketStream("1", "foo", 7, 2),
+		bucketStream("1", "bar", 1),
+	}
+	chart := ConvertChart(v1beta1.MonitoringDashboardChart{Name: "c", ChartType: v1beta1.ChartTypeHeatmap})
+	chart.FillHeatmap(v1beta1.MonitoringDashboardMetric{MetricName: "my_histogram", DisplayName: "Latency"}, prometheus.Metric{Matrix: matrix}, ConversionParams{Scale: 10})
+
+	assert.Empty(chart.Error)
+	assert.Empty(chart.Metrics)
+	assert.Len(chart.Heatmaps, 2)
+
+	bar := chart.Heatmaps[0]
+	assert.Equal(map[string]string{"__name__": "Latency", "app": "bar"}, bar.LabelSet)
+	assert.Equal([]string{"10"}, bar.Buckets)
+	assert.Equal([]int64{1000}, bar.Timestamps)
+	assert.Equal([][]float64{{1}}, bar.Values)
+
+	foo := chart.Heatmaps[1]
+	// Scale applies to bounds, not to counts
+	assert.Equal([]string{"1", "10", "+Inf"}, foo.Buckets)
+	assert.Equal([]int64{1000, 2000}, foo.Timestamps)
+	// Bucket "1" being lower than bucket "0.1" at 2000 counts for zero
+	assert.Equal([][]float64{{2, 3}, {5, 0}, {3, 9}}, foo.Values)
+
+	heatmaps := ConvertHeatmaps(pmod.Matrix{bucketStream("0.1", "foo", 1)}, map[string]string{}, ConversionParams{Scale: 3})
+	assert.Equal([]string{"0.3"}, heatmaps[0].Buckets)
+
+	chart.FillHeatmap(v1beta1.MonitoringDashboardMetric{MetricName: "my_histogram"}, prometheus.Metric{Err: errors.New("boom")}, ConversionParams{Scale: 1})
+	assert.Equal("error in metric my_histogram: boom", chart.Error)
+}
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"math"
+)
+
+// downsampleLTTB reduces values to the given number of points with the Largest-Triangle-Three-Buckets algorithm, which
+// keeps the visual shape of series, peaks included. First and last points are always kept.
+// See https://skemman.is/bitstream/1946/15343/3/SS_MSthesis.pdf
+func downsampleLTTB(values []SamplePair, threshold int) []SamplePair {
+	if threshold >= len(values) {
+		return values
+	}
+	if threshold < 3 {
+		// No room for buckets: keep the ends
+		if threshold == 2 {
+			return []SamplePair{values[0], values[len(values)-1]}
+		}
+		return values[:threshold]
+	}
+	sampled := make([]SamplePair, 0, threshold)
+	sampled = append(sampled, values[0])
+	// Points but the first and last ones are split into buckets, one point being selected per bucket
+	bucketSize := float64(len(values)-2) / float64(threshold-2)
+	selected := 0
+	for i := 0; i < threshold-2; i++ {
+		// The point selected in the bucket makes the largest triangle with the previously selected point
+		// and the average point of the next bucket
+		nextStart := int(float64(i+1)*bucketSize) + 1
+		nextEnd := int(float64(i+2)*bucketSize) + 1
+		if nextEnd > len(values) {
+			nextEnd = len(values)
+		}
+		avgX, avgY := 0.0, 0.0
+		for _, v := range values[nextStart:nextEnd] {
+			avgX += float64(v.Timestamp)
+			avgY += v.Value
+		}
+		count := float64(nextEnd - nextStart)
+		avgX, avgY = avgX/count, avgY/count
+
+		start := int(float64(i)*bucketSize) + 1
+		end := nextStart
+		ax, ay := float64(values[selected].Timestamp), values[selected].Value
+		maxArea := -1.0
+		next := start
+		for j := start; j < end; j++ {
+			area := math.Abs((ax-avgX)*(values[j].Value-ay) - (ax-float64(values[j].Timestamp))*(avgY-ay))
+			if area > maxArea {
+				maxArea = area
+				next = j
+			}
+		}
+		sampled = append(sampled, values[next])
+		selected = next
+	}
+	return append(sampled, values[len(values)-1])
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"math"
+	"testing"
+
+	pmod "github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownsampleLTTB(t *testing.T) {
+	assert := assert.New(t)
+
+	values := make([]SamplePair, 100)
+	for i := range values {
+		values[i] = SamplePair{Timestamp: int64(i) * 1000, Value: math.Sin(float64(i) / 10)}
+	}
+	// Spike, to be kept
+	values[42].Value = 10
+
+	sampled := downsampleLTTB(values, 10)
+	assert.Len(sampled, 10)
+	assert.Equal(values[0], sampled[0])
+	assert.Equal(values[99], sampled[9])
+	assert.Contains(sampled, values[42])
+	for i := 1; i < len(sampled); i++ {
+		assert.True(sampled[i].Timestamp > sampled[i-1].Timestamp)
+	}
+
+	assert.Equal(values, downsampleLTTB(values, 100))
+	assert.Equal(values, downsampleLTTB(values, 1000))
+	assert.Equal([]SamplePair{values[0], values[99]}, downsampleLTTB(values, 2))
+	assert.Equal([]SamplePair{values[0]}, downsampleLTTB(values, 1))
+}
+
+func TestConvertMatrixDownsampled(t *testing.T) {
+	assert := assert.New(t)
+
+	converted := ConvertMatrix(fakeLongMatrix(100), map[string]string{}, ConversionParams{Scale: 1.0, MaxDataPoints: 20})
+	assert.Len(converted, 1)
+	assert.Len(converted[0].Values, 20)
+
+	converted = ConvertMatrix(fakeLongMatrix(100), map[string]string{}, ConversionParams{Scale: 1.0})
+	assert.Len(converted[0].Values, 100)
+}
+
+func fakeLongMatrix(points int) pmod.Matrix {
+	series := &pmod.SampleStream{Metric: pmod.Metric{}}
+	for i := 0; i < points; i++ {
+		series.Values = append(series.Values, pmod.SamplePair{Timestamp: pmod.Time(i * 15000), Value: pmod.SampleValue(i % 7)})
+	}
+	return pmod.Matrix{series}
+}
@@ -4,13 +4,24 @@ import (
 	"github.com/kiali/k-charted/prometheus"
 )
 
+// AllClusters can be set as DashboardQuery.Cluster to fetch metrics from the default cluster and every configured cluster
+const AllClusters = "*"
+
 // DashboardQuery holds query parameters for a dashboard query
 type DashboardQuery struct {
 	prometheus.MetricsQuery
-	Namespace         string
-	LabelsFilters     map[string]string
+	// Cluster is the name of the cluster to query, as defined in config.Clusters or config.ClusterName, or AllClusters. Empty for the default cluster.
+	Cluster   string
+	Namespace string
+	// LabelsFilters are matchers applied to every query of the dashboard, in addition to the namespace
+	LabelsFilters     []prometheus.LabelMatcher
 	AdditionalLabels  []Aggregation
 	RawDataAggregator string
+	// Variables holds the requested values of dashboard variables, by variable name
+	Variables map[string]string
+	// MaxDataPoints is the maximum number of points per series, zero for no limit. The step and rate interval of
+	// queries are raised to stay within the limit.
+	MaxDataPoints int
 }
 
 // FillDefaults fills the struct with default parameters
@@ -0,0 +1,237 @@
+package prometheus
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+
+	"github.com/kiali/k-charted/config/extconfig"
+)
+
+// DefaultCacheMaxEntries is the default number of query results kept in cache
+const DefaultCacheMaxEntries = 1000
+
+var (
+	caches     = make(map[string]*resultCache)
+	cachesLock sync.Mutex
+)
+
+// CacheStats holds statistics of a query results cache
+type CacheStats struct {
+	// Hits is the number of queries served from cache
+	Hits uint64 `json:"hits"`
+	// Misses is the number of queries sent to Prometheus
+	Misses uint64 `json:"misses"`
+	// Coalesced is the number of queries that waited for an identical query in flight instead of being sent
+	Coalesced uint64 `json:"coalesced"`
+	// Entries is the number of results currently in cache
+	Entries int `json:"entries"`
+}
+
+// resultCache is an LRU cache of range query results, with a TTL, which coalesces concurrent identical queries.
+// It is shared by all the clients to the same server.
+type resultCache struct {
+	ttl        time.Duration
+	maxEntries int
+	lock       sync.Mutex
+	entries    map[string]*list.Element
+	lru        *list.List
+	inFlight   map[string]*pendingQuery
+	hits       uint64
+	misses     uint64
+	coalesced  uint64
+}
+
+type cacheEntry struct {
+	key     string
+	matrix  model.Matrix
+	expires time.Time
+}
+
+type pendingQuery struct {
+	done   chan struct{}
+	result Metric
+	// aborted is set when the query was aborted because its caller's context was done
+	aborted bool
+}
+
+// getResultCache returns the cache shared for the given configuration, or nil when caching is disabled
+func getResultCache(cfg extconfig.PrometheusConfig) *resultCache {
+	key, maxEntries, enabled := resultCacheKey(cfg)
+	if !enabled {
+		return nil
+	}
+	cachesLock.Lock()
+	defer cachesLock.Unlock()
+	if cache, ok := caches[key]; ok {
+		return cache
+	}
+	cache := newResultCache(cfg.CacheTTL, maxEntries)
+	caches[key] = cache
+	return cache
+}
+
+// resultCacheKey identifies the cache of a configuration. Besides the server, it holds everything changing query results
+// other than the tenant, which is part of the entries keys: clients authenticated differently, or sending other Thanos
+// parameters, don't share results.
+func resultCacheKey(cfg extconfig.PrometheusConfig) (string, int, bool) {
+	if cfg.CacheTTL <= 0 {
+		return "", 0, false
+	}
+	maxEntries := cfg.CacheMaxEntries
+	if maxEntries <= 0 {
+		maxEntries = DefaultCacheMaxEntries
+	}
+	key := fmt.Sprintf("%s|%v|%d|%s|%s|%s|%s", cfg.URL, cfg.CacheTTL, maxEntries, authIdentity(cfg.Auth), cfg.TenantHeader,
+		optionalBool(cfg.PartialResponse), optionalBool(cfg.Dedup))
+	return key, maxEntries, true
+}
+
+// authIdentity identifies the credentials of a configuration, hashed so that they aren't kept in clear in the caches keys
+func authIdentity(auth extconfig.Auth) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%q|%q|%q|%q", auth.Type, auth.Username, auth.Password, auth.Token)))
+	return fmt.Sprintf("%x", hash)
+}
+
+func optionalBool(value *bool) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatBool(*value)
+}
+
+// lookupResultCache returns the cache shared for the given configuration, without creating it. Nil when there is none.
+func lookupResultCache(cfg extconfig.PrometheusConfig) *resultCache {
+	key, _, enabled := resultCacheKey(cfg)
+	if !enabled {
+		return nil
+	}
+	cachesLock.Lock()
+	defer cachesLock.Unlock()
+	return caches[key]
+}
+
+// GetCacheStats returns statistics of the query results cache shared by the clients with the given configuration.
+// Zero when caching is disabled, or when no client was created yet with this configuration.
+func GetCacheStats(cfg extconfig.PrometheusConfig) CacheStats {
+	if cache := lookupResultCache(cfg); cache != nil {
+		return cache.stats()
+	}
+	return CacheStats{}
+}
+
+func newResultCache(ttl time.Duration, maxEntries int) *resultCache {
+	return &resultCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+		inFlight:   make(map[string]*pendingQuery),
+	}
+}
+
+// cacheKey identifies a range query; the tenant is part of it as results differ between tenants
+func cacheKey(tenant, query string, bounds v1.Range) string {
+	return fmt.Sprintf("%s|%s|%d|%d|%d", tenant, query, bounds.Start.Unix(), bounds.End.Unix(), int64(bounds.Step.Seconds()))
+}
+
+// alignRange aligns start and end on step, so that close queries share the same cache key
+func alignRange(bounds v1.Range) v1.Range {
+	step := int64(bounds.Step.Seconds())
+	if step <= 0 {
+		return bounds
+	}
+	return v1.Range{
+		Start: time.Unix((bounds.Start.Unix()/step)*step, 0),
+		End:   time.Unix((bounds.End.Unix()/step)*step, 0),
+		Step:  bounds.Step,
+	}
+}
+
+// get returns the cached result for key, or runs the query with ctx. Concurrent calls for the same key share a single query,
+// which is run with the context of the first caller. Errors aren't cached.
+func (c *resultCache) get(ctx context.Context, key string, query func(context.Context) Metric) Metric {
+	c.lock.Lock()
+	if elt, ok := c.entries[key]; ok {
+		entry := elt.Value.(*cacheEntry)
+		if time.Now().Before(entry.expires) {
+			c.lru.MoveToFront(elt)
+			c.hits++
+			c.lock.Unlock()
+			return Metric{Matrix: copyMatrix(entry.matrix)}
+		}
+		c.removeElement(elt)
+	}
+	if pending, ok := c.inFlight[key]; ok {
+		c.coalesced++
+		c.lock.Unlock()
+		select {
+		case <-pending.done:
+		case <-ctx.Done():
+			return Metric{Err: ctx.Err()}
+		}
+		if pending.aborted && ctx.Err() == nil {
+			// The query was aborted on behalf of another caller: run it for this one, with its own context
+			return query(ctx)
+		}
+		return Metric{Matrix: copyMatrix(pending.result.Matrix), Err: pending.result.Err}
+	}
+	pending := &pendingQuery{done: make(chan struct{})}
+	c.inFlight[key] = pending
+	c.misses++
+	c.lock.Unlock()
+
+	pending.result = query(ctx)
+	pending.aborted = ctx.Err() != nil
+
+	c.lock.Lock()
+	delete(c.inFlight, key)
+	if pending.result.Err == nil {
+		c.add(key, pending.result.Matrix)
+	}
+	c.lock.Unlock()
+	close(pending.done)
+	return Metric{Matrix: copyMatrix(pending.result.Matrix), Err: pending.result.Err}
+}
+
+// add stores a result, evicting the least recently used entries when full. Lock must be held.
+func (c *resultCache) add(key string, matrix model.Matrix) {
+	if elt, ok := c.entries[key]; ok {
+		c.removeElement(elt)
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, matrix: matrix, expires: time.Now().Add(c.ttl)})
+	for c.lru.Len() > c.maxEntries {
+		c.removeElement(c.lru.Back())
+	}
+}
+
+func (c *resultCache) removeElement(elt *list.Element) {
+	c.lru.Remove(elt)
+	delete(c.entries, elt.Value.(*cacheEntry).key)
+}
+
+func (c *resultCache) stats() CacheStats {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return CacheStats{
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Coalesced: c.coalesced,
+		Entries:   c.lru.Len(),
+	}
+}
+
+// copyMatrix returns a new slice of the same series, as callers may reorder it. Series themselves must not be modified.
+func copyMatrix(matrix model.Matrix) model.Matrix {
+	if matrix == nil {
+		return nil
+	}
+	return append(make(model.Matrix, 0, len(matrix)), matrix...)
+}
@@ -0,0 +1,194 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiali/k-charted/config/extconfig"
+)
+
+func TestResultCacheHitAndEviction(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := newResultCache(time.Minute, 2)
+	calls := 0
+	query := func(context.Context) Metric {
+		calls++
+		return Metric{Matrix: model.Matrix{&model.SampleStream{}}}
+	}
+
+	cache.get(context.Background(), "a", query)
+	cache.get(context.Background(), "a", query)
+	cache.get(context.Background(), "b", query)
+	cache.get(context.Background(), "c", query)
+	// "a" was evicted as least recently used
+	cache.get(context.Background(), "a", query)
+
+	assert.Equal(4, calls)
+	assert.Equal(CacheStats{Hits: 1, Misses: 4, Entries: 2}, cache.stats())
+}
+
+func TestResultCacheExpiryAndErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := newResultCache(20*time.Millisecond, 10)
+	calls := 0
+	cache.get(context.Background(), "a", func(context.Context) Metric { calls++; return Metric{} })
+	time.Sleep(30 * time.Millisecond)
+	cache.get(context.Background(), "a", func(context.Context) Metric { calls++; return Metric{} })
+	assert.Equal(2, calls)
+
+	failing := func(context.Context) Metric { calls++; return Metric{Err: errors.New("failure")} }
+	assert.NotNil(cache.get(context.Background(), "b", failing).Err)
+	assert.NotNil(cache.get(context.Background(), "b", failing).Err)
+	assert.Equal(4, calls)
+}
+
+func TestResultCacheCoalescing(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := newResultCache(time.Minute, 10)
+	var calls int32
+	release := make(chan struct{})
+	query := func(context.Context) Metric {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return Metric{Matrix: model.Matrix{&model.SampleStream{}, &model.SampleStream{}}}
+	}
+
+	wg := sync.WaitGroup{}
+	results := make([]Metric, 5)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = cache.get(context.Background(), "a", query)
+		}(i)
+	}
+	// Let every caller reach the cache before releasing the query
+	for cache.stats().Coalesced < 4 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	assert.Equal(int32(1), calls)
+	for _, result := range results {
+		assert.Len(result.Matrix, 2)
+	}
+	// Callers get their own slice
+	results[0].Matrix[0] = nil
+	assert.NotNil(results[1].Matrix[0])
+}
+
+func TestResultCacheAbortedLeader(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := newResultCache(time.Minute, 10)
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	leaderDone := make(chan struct{})
+	go func() {
+		defer close(leaderDone)
+		cache.get(leaderCtx, "a", func(ctx context.Context) Metric {
+			close(started)
+			<-ctx.Done()
+			return Metric{Err: ctx.Err()}
+		})
+	}()
+	<-started
+
+	waiterResult := make(chan Metric)
+	go func() {
+		waiterResult <- cache.get(context.Background(), "a", func(ctx context.Context) Metric {
+			// The retry must not run with the leader's cancelled context
+			if ctx.Err() != nil {
+				return Metric{Err: ctx.Err()}
+			}
+			return Metric{Matrix: model.Matrix{&model.SampleStream{}}}
+		})
+	}()
+	for cache.stats().Coalesced < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-leaderDone
+
+	result := <-waiterResult
+	assert.Nil(result.Err)
+	assert.Len(result.Matrix, 1)
+}
+
+func TestAlignRange(t *testing.T) {
+	bounds := alignRange(v1.Range{Start: time.Unix(1007, 0), End: time.Unix(1807, 0), Step: 15 * time.Second})
+	assert.Equal(t, v1.Range{Start: time.Unix(1005, 0), End: time.Unix(1800, 0), Step: 15 * time.Second}, bounds)
+}
+
+func TestClientCache(t *testing.T) {
+	assert := assert.New(t)
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[]}}`))
+	}))
+	defer server.Close()
+
+	cfg := extconfig.PrometheusConfig{URL: server.URL, CacheTTL: time.Minute}
+	// Reading stats doesn't create a cache
+	assert.Equal(CacheStats{}, GetCacheStats(cfg))
+	assert.Nil(lookupResultCache(cfg))
+
+	client, err := NewClient(cfg)
+	assert.Nil(err)
+
+	q := MetricsQuery{}
+	q.FillDefaults()
+	client.FetchRateRange(context.Background(), "my_counter", "{}", "", &q)
+	client.FetchRateRange(context.Background(), "my_counter", "{}", "", &q)
+	client.ForNamespace("other").FetchRateRange(context.Background(), "my_counter", "{}", "", &q)
+
+	assert.Equal(int32(1), requests)
+	assert.Equal(CacheStats{Hits: 2, Misses: 1, Entries: 1}, GetCacheStats(cfg))
+}
+
+func TestResultCacheKey(t *testing.T) {
+	assert := assert.New(t)
+
+	enabled := true
+	cfg := extconfig.PrometheusConfig{URL: "http://prometheus:9090", CacheTTL: time.Minute}
+	key, maxEntries, ok := resultCacheKey(cfg)
+	assert.True(ok)
+	assert.Equal(DefaultCacheMaxEntries, maxEntries)
+
+	// Clients authenticated differently or sending other Thanos parameters don't share results
+	for _, other := range []extconfig.PrometheusConfig{
+		{URL: cfg.URL, CacheTTL: cfg.CacheTTL, Auth: extconfig.Auth{Type: extconfig.AuthTypeBearer, Token: "token1"}},
+		{URL: cfg.URL, CacheTTL: cfg.CacheTTL, Auth: extconfig.Auth{Type: extconfig.AuthTypeBearer, Token: "token2"}},
+		{URL: cfg.URL, CacheTTL: cfg.CacheTTL, Auth: extconfig.Auth{Type: extconfig.AuthTypeBasic, Username: "user", Password: "pwd"}},
+		{URL: cfg.URL, CacheTTL: cfg.CacheTTL, PartialResponse: &enabled},
+		{URL: cfg.URL, CacheTTL: cfg.CacheTTL, Dedup: &enabled},
+	} {
+		otherKey, _, _ := resultCacheKey(other)
+		assert.NotEqual(key, otherKey)
+		assert.NotContains(otherKey, "token1")
+		assert.NotContains(otherKey, "pwd")
+	}
+	tokenKey1, _, _ := resultCacheKey(extconfig.PrometheusConfig{URL: cfg.URL, CacheTTL: cfg.CacheTTL, Auth: extconfig.Auth{Type: extconfig.AuthTypeBearer, Token: "token1"}})
+	tokenKey2, _, _ := resultCacheKey(extconfig.PrometheusConfig{URL: cfg.URL, CacheTTL: cfg.CacheTTL, Auth: extconfig.Auth{Type: extconfig.AuthTypeBearer, Token: "token2"}})
+	assert.NotEqual(tokenKey1, tokenKey2)
+
+	_, _, ok = resultCacheKey(extconfig.PrometheusConfig{URL: cfg.URL})
+	assert.False(ok)
+}
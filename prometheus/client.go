@@ -4,6 +4,10 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/api"
@@ -12,21 +16,36 @@ import (
 
 	"github.com/kiali/k-charted/config/extconfig"
 	"github.com/kiali/k-charted/httputil"
+	"github.com/kiali/k-charted/prometheus/promql"
 )
 
+// ClientInterface is the high level API to Prometheus. Queries are aborted when the provided context is cancelled or expires.
+// Fetch methods query the range of the MetricsQuery, unless MetricsQuery.Instant is set: queries are then evaluated at
+// MetricsQuery.End only, and results are returned as vectors.
 type ClientInterface interface {
-	FetchHistogramRange(metricName, labels, grouping string, q *MetricsQuery) Histogram
-	FetchRange(metricName, labels, grouping, aggregator string, q *MetricsQuery) Metric
-	FetchRateRange(metricName, labels, grouping string, q *MetricsQuery) Metric
-	GetMetricsForLabels(labels []string) ([]string, error)
+	FetchHistogramRange(ctx context.Context, metricName, labels, grouping string, q *MetricsQuery) Histogram
+	FetchNativeHistogramRange(ctx context.Context, metricName, labels, grouping string, q *MetricsQuery) Histogram
+	FetchHistogramBuckets(ctx context.Context, metricName, labels, grouping string, q *MetricsQuery) Metric
+	FetchSummaryRange(ctx context.Context, metricName, labels, grouping string, q *MetricsQuery) Histogram
+	FetchRange(ctx context.Context, metricName, labels, grouping, aggregator string, q *MetricsQuery) Metric
+	FetchRateRange(ctx context.Context, metricName, labels, grouping string, q *MetricsQuery) Metric
+	FetchExprRange(ctx context.Context, expr, labels, grouping string, q *MetricsQuery) Metric
+	GetMetricsForLabels(ctx context.Context, labels []string) ([]string, error)
+	GetLabelValues(ctx context.Context, label string, series []string) ([]string, error)
+	ForNamespace(namespace string) ClientInterface
 }
 
 // Client for Prometheus API.
 // It hides the way we query Prometheus offering a layer with a high level defined API.
 type Client struct {
 	ClientInterface
-	p8s api.Client
-	api v1.API
+	p8s    api.Client
+	api    v1.API
+	config extconfig.PrometheusConfig
+	// tenant sent with every request, for multi-tenant backends
+	tenant string
+	pool   *queryPool
+	cache  *resultCache
 }
 
 // NewClient creates a new client to the Prometheus API.
@@ -37,76 +56,272 @@ func NewClient(cfg extconfig.PrometheusConfig) (*Client, error) {
 	if err != nil {
 		return nil, err
 	}
-	clientConfig.RoundTripper = transportConfig
+	clientConfig.RoundTripper = newTenantRoundTripper(cfg, transportConfig)
 
 	p8s, err := api.NewClient(clientConfig)
 	if err != nil {
 		return nil, err
 	}
-	client := Client{p8s: p8s, api: v1.NewAPI(p8s)}
+	client := Client{p8s: p8s, api: v1.NewAPI(p8s), config: cfg, tenant: cfg.Tenant, pool: getQueryPool(cfg.URL, cfg.MaxConcurrentQueries), cache: getResultCache(cfg)}
 	return &client, nil
 }
 
+// ForNamespace returns a client for queries on the given namespace, which is sent with the tenant mapped to that namespace.
+// The underlying connection is shared.
+func (in *Client) ForNamespace(namespace string) ClientInterface {
+	client := *in
+	client.tenant = tenantFor(in.config, namespace)
+	return &client
+}
+
+func (in *Client) context(ctx context.Context) context.Context {
+	return withTenant(ctx, in.tenant)
+}
+
 // FetchRange fetches a simple metric (gauge or counter) in given range
-func (in *Client) FetchRange(metricName, labels, grouping, aggregator string, q *MetricsQuery) Metric {
-	query := fmt.Sprintf("%s(%s)", aggregator, metricName+labels)
-	if grouping != "" {
-		query += fmt.Sprintf(" by (%s)", grouping)
+func (in *Client) FetchRange(ctx context.Context, metricName, labels, grouping, aggregator string, q *MetricsQuery) Metric {
+	p, err := parseQueryParams(metricName, labels, grouping, q)
+	if err == nil && !promql.IsAggregation(aggregator) {
+		err = fmt.Errorf("invalid aggregator '%s'", aggregator)
+	}
+	if err != nil {
+		return Metric{Err: err}
 	}
-	query = roundSignificant(query, 0.001)
-	return in.fetchRange(query, q.Range)
+	// Example: round(sum(my_gauge{foo=bar}) by (baz), 0.001)
+	query := promql.Aggregate(aggregator, p.metric(metricName), p.grouping)
+	return in.fetchQuery(ctx, round(query, q), q)
 }
 
 // FetchRateRange fetches a counter's rate in given range
-func (in *Client) FetchRateRange(metricName, labels, grouping string, q *MetricsQuery) Metric {
-	var query string
+func (in *Client) FetchRateRange(ctx context.Context, metricName, labels, grouping string, q *MetricsQuery) Metric {
+	p, err := parseQueryParams(metricName, labels, grouping, q)
+	if err != nil {
+		return Metric{Err: err}
+	}
 	// Example: round(sum(rate(my_counter{foo=bar}[5m])) by (baz), 0.001)
-	innerQuery := fmt.Sprintf("%s%s[%s]", metricName, labels, q.RateInterval)
-	if grouping == "" {
-		query = fmt.Sprintf("sum(%s(%s))", q.RateFunc, innerQuery)
-	} else {
-		query = fmt.Sprintf("sum(%s(%s)) by (%s)", q.RateFunc, innerQuery, grouping)
+	query := promql.Aggregate("sum", p.rate(p.rateFunc, metricName), p.grouping)
+	return in.fetchQuery(ctx, round(query, q), q)
+}
+
+// FetchExprRange fetches the result of a PromQL expression template in given range.
+// Placeholders $__labels, $__matchers, $__by and $__rate_interval are replaced in the template, see promql.ExpandExpr.
+func (in *Client) FetchExprRange(ctx context.Context, expr, labels, grouping string, q *MetricsQuery) Metric {
+	if _, err := parseQueryParams("", labels, grouping, q); err != nil {
+		return Metric{Err: err}
+	}
+	expanded := promql.ExpandExpr(expr, labels, grouping, q.RateInterval)
+	query, err := promql.ParseExpr(expanded)
+	if err != nil {
+		return Metric{Err: fmt.Errorf("invalid expression %s: %v", expanded, err), Query: expanded}
 	}
-	query = roundSignificant(query, 0.001)
-	return in.fetchRange(query, q.Range)
+	return in.fetchQuery(ctx, round(query, q), q)
 }
 
 // FetchHistogramRange fetches bucketed metric as histogram in given range
-func (in *Client) FetchHistogramRange(metricName, labels, grouping string, q *MetricsQuery) Histogram {
-	histogram := make(Histogram)
-
-	// Note: the p8s queries are not run in parallel here, but they are at the caller's place.
-	//	This is because we may not want to create too many threads in the lowest layer
+func (in *Client) FetchHistogramRange(ctx context.Context, metricName, labels, grouping string, q *MetricsQuery) Histogram {
+	p, err := parseQueryParams(metricName, labels, grouping, q)
+	if err != nil {
+		return errorHistogram(q, err)
+	}
+	queries := make(map[string]promql.Expr)
 	if q.Avg {
-		groupingAvg := ""
-		if grouping != "" {
-			groupingAvg = fmt.Sprintf(" by (%s)", grouping)
-		}
-		// Average
 		// Example: sum(rate(my_histogram_sum{foo=bar}[5m])) by (baz) / sum(rate(my_histogram_count{foo=bar}[5m])) by (baz)
-		query := fmt.Sprintf("sum(rate(%s_sum%s[%s]))%s / sum(rate(%s_count%s[%s]))%s",
-			metricName, labels, q.RateInterval, groupingAvg, metricName, labels, q.RateInterval, groupingAvg)
-		query = roundSignificant(query, 0.001)
-		histogram["avg"] = in.fetchRange(query, q.Range)
+		queries["avg"] = round(p.avgFromSumCount(metricName), q)
+	}
+	for i, quantile := range q.Quantiles {
+		// Example: round(histogram_quantile(0.5, sum(rate(my_histogram_bucket{foo=bar}[5m])) by (le,baz)), 0.001)
+		buckets := promql.Aggregate("sum", p.rate("rate", metricName+"_bucket"), append([]string{"le"}, p.grouping...))
+		query := promql.Func("histogram_quantile", promql.Number(p.quantiles[i]), buckets)
+		queries[quantile] = round(query, q)
 	}
+	return in.fetchStats(ctx, queries, q)
+}
 
-	groupingQuantile := ""
+// FetchNativeHistogramRange fetches a native (sparse) histogram metric as histogram in given range
+func (in *Client) FetchNativeHistogramRange(ctx context.Context, metricName, labels, grouping string, q *MetricsQuery) Histogram {
+	p, err := parseQueryParams(metricName, labels, grouping, q)
+	if err != nil {
+		return errorHistogram(q, err)
+	}
+	queries := make(map[string]promql.Expr)
+	histogram := promql.Aggregate("sum", p.rate("rate", metricName), p.grouping)
+	if q.Avg {
+		// Example: histogram_avg(sum(rate(my_histogram{foo=bar}[5m])) by (baz))
+		queries["avg"] = round(promql.Func("histogram_avg", histogram), q)
+	}
+	for i, quantile := range q.Quantiles {
+		// Example: histogram_quantile(0.5, sum(rate(my_histogram{foo=bar}[5m])) by (baz))
+		query := promql.Func("histogram_quantile", promql.Number(p.quantiles[i]), histogram)
+		queries[quantile] = round(query, q)
+	}
+	return in.fetchStats(ctx, queries, q)
+}
+
+// FetchSummaryRange fetches a summary metric as histogram in given range. Quantiles are read from the "quantile" label;
+// as they can't be aggregated, quantiles of several series are averaged.
+func (in *Client) FetchSummaryRange(ctx context.Context, metricName, labels, grouping string, q *MetricsQuery) Histogram {
+	p, err := parseQueryParams(metricName, labels, grouping, q)
+	if err != nil {
+		return errorHistogram(q, err)
+	}
+	queries := make(map[string]promql.Expr)
+	if q.Avg {
+		// Example: sum(rate(my_summary_sum{foo=bar}[5m])) by (baz) / sum(rate(my_summary_count{foo=bar}[5m])) by (baz)
+		queries["avg"] = round(p.avgFromSumCount(metricName), q)
+	}
+	for _, quantile := range q.Quantiles {
+		// Example: avg(my_summary{quantile="0.5",foo=bar}) by (baz)
+		matchers := append(promql.Selector{{Name: "quantile", Type: promql.MatchEqual, Value: quantile}}, p.matchers...)
+		query := promql.Aggregate("avg", promql.Metric(metricName, matchers), p.grouping)
+		queries[quantile] = round(query, q)
+	}
+	return in.fetchStats(ctx, queries, q)
+}
+
+// FetchHistogramBuckets fetches the rates of each bucket of a classic histogram in given range, with the "le" label.
+// Buckets are cumulative, as in Prometheus.
+func (in *Client) FetchHistogramBuckets(ctx context.Context, metricName, labels, grouping string, q *MetricsQuery) Metric {
+	p, err := parseQueryParams(metricName, labels, grouping, q)
+	if err != nil {
+		return Metric{Err: err}
+	}
+	// Example: sum(rate(my_histogram_bucket{foo=bar}[5m])) by (le,baz)
+	query := promql.Aggregate("sum", p.rate("rate", metricName+"_bucket"), append([]string{"le"}, p.grouping...))
+	return in.fetchQuery(ctx, query, q)
+}
+
+// queryParams holds the checked parameters of the Fetch methods
+type queryParams struct {
+	matchers  promql.Selector
+	grouping  []string
+	quantiles []float64
+	// rateInterval is the range of rates
+	rateInterval string
+	rateFunc     string
+}
+
+// parseQueryParams checks the parameters of the Fetch methods, which can't be trusted as they partly come from the query string,
+// so that nothing can be injected in queries
+func parseQueryParams(metricName, labels, grouping string, q *MetricsQuery) (queryParams, error) {
+	if metricName != "" && !model.IsValidMetricName(model.LabelValue(metricName)) {
+		return queryParams{}, fmt.Errorf("invalid metric name '%s'", metricName)
+	}
+	matchers, err := promql.ParseSelector(labels)
+	if err != nil {
+		return queryParams{}, fmt.Errorf("invalid labels %s: %v", labels, err)
+	}
+	p := queryParams{matchers: matchers, rateInterval: q.RateInterval}
 	if grouping != "" {
-		groupingQuantile = fmt.Sprintf(",%s", grouping)
+		for _, label := range strings.Split(grouping, ",") {
+			label = strings.TrimSpace(label)
+			if !model.LabelName(label).IsValid() {
+				return queryParams{}, fmt.Errorf("invalid grouping label '%s'", label)
+			}
+			p.grouping = append(p.grouping, label)
+		}
+	}
+	if q.RateInterval != "" && !promql.IsDuration(q.RateInterval) {
+		return queryParams{}, fmt.Errorf("invalid rate interval '%s'", q.RateInterval)
+	}
+	p.rateFunc = q.RateFunc
+	if p.rateFunc == "" {
+		p.rateFunc = "rate"
+	} else if !promql.IsFunction(p.rateFunc) {
+		return queryParams{}, fmt.Errorf("invalid rate function '%s'", q.RateFunc)
 	}
 	for _, quantile := range q.Quantiles {
-		// Example: round(histogram_quantile(0.5, sum(rate(my_histogram_bucket{foo=bar}[5m])) by (le,baz)), 0.001)
-		query := fmt.Sprintf("histogram_quantile(%s, sum(rate(%s_bucket%s[%s])) by (le%s))",
-			quantile, metricName, labels, q.RateInterval, groupingQuantile)
-		query = roundSignificant(query, 0.001)
-		histogram[quantile] = in.fetchRange(query, q.Range)
+		value, err := strconv.ParseFloat(quantile, 64)
+		if err != nil {
+			return queryParams{}, fmt.Errorf("invalid quantile '%s'", quantile)
+		}
+		p.quantiles = append(p.quantiles, value)
 	}
+	return p, nil
+}
+
+func (p queryParams) metric(name string) *promql.VectorSelector {
+	return promql.Metric(name, p.matchers)
+}
+
+// rate returns a rate function over the metric, e.g. rate(my_counter{foo=bar}[5m])
+func (p queryParams) rate(rateFunc, name string) promql.Expr {
+	return promql.Func(rateFunc, promql.Range(p.metric(name), p.rateInterval))
+}
+
+// avgFromSumCount returns the average of a histogram or summary, from its _sum and _count series
+func (p queryParams) avgFromSumCount(metricName string) promql.Expr {
+	sum := promql.Aggregate("sum", p.rate("rate", metricName+"_sum"), p.grouping)
+	count := promql.Aggregate("sum", p.rate("rate", metricName+"_count"), p.grouping)
+	return promql.Binary("/", sum, count)
+}
+
+// errorHistogram returns the error for every requested statistic
+func errorHistogram(q *MetricsQuery, err error) Histogram {
+	histogram := make(Histogram)
+	if q.Avg {
+		histogram["avg"] = Metric{Err: err}
+	}
+	for _, quantile := range q.Quantiles {
+		histogram[quantile] = Metric{Err: err}
+	}
+	return histogram
+}
 
+// fetchStats runs the queries of histogram statistics concurrently, bounded by the queries pool
+func (in *Client) fetchStats(ctx context.Context, queries map[string]promql.Expr, q *MetricsQuery) Histogram {
+	histogram := make(Histogram)
+	lock := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for stat, query := range queries {
+		wg.Add(1)
+		go func(stat string, query promql.Expr) {
+			defer wg.Done()
+			metric := in.fetchQuery(ctx, query, q)
+			lock.Lock()
+			histogram[stat] = metric
+			lock.Unlock()
+		}(stat, query)
+	}
+	wg.Wait()
 	return histogram
 }
 
-func (in *Client) fetchRange(query string, bounds v1.Range) Metric {
-	result, err := in.api.QueryRange(context.Background(), query, bounds)
+// fetchQuery renders and checks a query before fetching it, over the range or at the end of the MetricsQuery.
+// The query text is returned along with the result.
+func (in *Client) fetchQuery(ctx context.Context, expr promql.Expr, q *MetricsQuery) Metric {
+	query := expr.String()
+	if err := promql.Check(query); err != nil {
+		return Metric{Err: err, Query: query}
+	}
+	var metric Metric
+	if q.Instant {
+		metric = in.queryInstant(ctx, query, q.End)
+	} else {
+		metric = in.fetchRange(ctx, query, q.Range)
+	}
+	metric.Query = query
+	return metric
+}
+
+func (in *Client) fetchRange(ctx context.Context, query string, bounds v1.Range) Metric {
+	if in.cache == nil {
+		return in.queryRange(ctx, query, bounds)
+	}
+	bounds = alignRange(bounds)
+	if in.config.CacheSplitInterval > 0 {
+		return in.fetchSplitRange(ctx, query, bounds)
+	}
+	return in.cache.get(ctx, cacheKey(in.tenant, query, bounds), func(ctx context.Context) Metric {
+		return in.queryRange(ctx, query, bounds)
+	})
+}
+
+func (in *Client) queryRange(ctx context.Context, query string, bounds v1.Range) Metric {
+	if err := in.pool.acquire(ctx); err != nil {
+		return Metric{Err: err}
+	}
+	defer in.pool.release()
+	result, err := in.api.QueryRange(in.context(ctx), query, bounds)
 	if err != nil {
 		return Metric{Err: err}
 	}
@@ -117,12 +332,37 @@ func (in *Client) fetchRange(query string, bounds v1.Range) Metric {
 	return Metric{Err: fmt.Errorf("invalid query, matrix expected: %s", query)}
 }
 
+// queryInstant evaluates a query at the given time. Results aren't cached, as they're mostly about the current time.
+func (in *Client) queryInstant(ctx context.Context, query string, at time.Time) Metric {
+	if err := in.pool.acquire(ctx); err != nil {
+		return Metric{Err: err}
+	}
+	defer in.pool.release()
+	result, err := in.api.Query(in.context(ctx), query, at)
+	if err != nil {
+		return Metric{Err: err}
+	}
+	switch result.Type() {
+	case model.ValVector:
+		return Metric{Vector: result.(model.Vector)}
+	case model.ValScalar:
+		// e.g. expressions made of numbers only
+		scalar := result.(*model.Scalar)
+		return Metric{Vector: model.Vector{{Metric: model.Metric{}, Value: scalar.Value, Timestamp: scalar.Timestamp}}}
+	}
+	return Metric{Err: fmt.Errorf("invalid query, vector expected: %s", query)}
+}
+
 // GetMetricsForLabels returns a list of metrics existing for the provided labels set
-func (in *Client) GetMetricsForLabels(labels []string) ([]string, error) {
+func (in *Client) GetMetricsForLabels(ctx context.Context, labels []string) ([]string, error) {
 	// Arbitrarily set time range. Meaning that discovery works with metrics produced within last hour
 	end := time.Now()
 	start := end.Add(-time.Hour)
-	results, err := in.api.Series(context.Background(), labels, start, end)
+	if err := in.pool.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer in.pool.release()
+	results, err := in.api.Series(in.context(ctx), labels, start, end)
 	if err != nil {
 		return nil, err
 	}
@@ -135,7 +375,46 @@ func (in *Client) GetMetricsForLabels(labels []string) ([]string, error) {
 	return names, nil
 }
 
+// GetLabelValues returns the sorted, distinct values of a label among the series matching the provided selectors
+func (in *Client) GetLabelValues(ctx context.Context, label string, series []string) ([]string, error) {
+	// Same time range as for discovery: values from series produced within last hour
+	end := time.Now()
+	start := end.Add(-time.Hour)
+	if err := in.pool.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer in.pool.release()
+	results, err := in.api.Series(in.context(ctx), series, start, end)
+	if err != nil {
+		return nil, err
+	}
+	unique := make(map[string]bool)
+	values := []string{}
+	for _, labelSet := range results {
+		if value, ok := labelSet[model.LabelName(label)]; ok && !unique[string(value)] {
+			unique[string(value)] = true
+			values = append(values, string(value))
+		}
+	}
+	sort.Strings(values)
+	return values, nil
+}
+
+// round rounds the values of a query with the requested precision, if any, see roundSignificant
+func round(query promql.Expr, q *MetricsQuery) promql.Expr {
+	precision := DefaultRounding
+	if q.Rounding != nil {
+		precision = *q.Rounding
+	}
+	if precision <= 0 {
+		return query
+	}
+	return roundSignificant(query, precision)
+}
+
 // roundSignificant will output promQL that performs rounding only if the resulting value is significant, that is, higher than the requested precision
-func roundSignificant(innerQuery string, precision float64) string {
-	return fmt.Sprintf("round(%s, %f) > %f or %s", innerQuery, precision, precision, innerQuery)
+func roundSignificant(innerQuery promql.Expr, precision float64) promql.Expr {
+	// Example: round(my_query, 0.001) > 0.001 or my_query
+	round := promql.Func("round", innerQuery, promql.Number(precision))
+	return promql.Binary("or", promql.Binary(">", round, promql.Number(precision)), innerQuery)
 }
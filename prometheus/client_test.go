@@ -0,0 +1,210 @@
+package prometheus
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiali/k-charted/config/extconfig"
+)
+
+func TestFetchRangeCancelled(t *testing.T) {
+	assert := assert.New(t)
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client, err := NewClient(extconfig.PrometheusConfig{URL: server.URL})
+	assert.Nil(err)
+
+	q := MetricsQuery{}
+	q.FillDefaults()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	metric := client.FetchRange(ctx, "my_gauge", "{}", "", "sum", &q)
+	assert.NotNil(metric.Err)
+	assert.Equal(context.DeadlineExceeded, ctx.Err())
+}
+
+func TestFetchNativeHistogramRange(t *testing.T) {
+	assert := assert.New(t)
+
+	lock := sync.Mutex{}
+	queries := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		queries = append(queries, r.FormValue("query"))
+		lock.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[]}}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(extconfig.PrometheusConfig{URL: server.URL})
+	assert.Nil(err)
+
+	q := MetricsQuery{}
+	q.FillDefaults()
+	q.Quantiles = []string{"0.99"}
+	histo := client.FetchNativeHistogramRange(context.Background(), "my_histogram", `{app="foo"}`, "code", &q)
+
+	assert.Len(histo, 2)
+	assert.ElementsMatch([]string{
+		`round(histogram_avg(sum(rate(my_histogram{app="foo"}[1m])) by (code)), 0.001) > 0.001 or histogram_avg(sum(rate(my_histogram{app="foo"}[1m])) by (code))`,
+		`round(histogram_quantile(0.99, sum(rate(my_histogram{app="foo"}[1m])) by (code)), 0.001) > 0.001 or histogram_quantile(0.99, sum(rate(my_histogram{app="foo"}[1m])) by (code))`,
+	}, queries)
+}
+
+func TestFetchSummaryRange(t *testing.T) {
+	assert := assert.New(t)
+
+	lock := sync.Mutex{}
+	queries := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		queries = append(queries, r.FormValue("query"))
+		lock.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[]}}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(extconfig.PrometheusConfig{URL: server.URL})
+	assert.Nil(err)
+
+	q := MetricsQuery{}
+	q.FillDefaults()
+	q.Quantiles = []string{"0.99"}
+	histo := client.FetchSummaryRange(context.Background(), "my_summary", `{app="foo"}`, "code", &q)
+	histo2 := client.FetchSummaryRange(context.Background(), "my_summary", "{}", "", &MetricsQuery{Quantiles: []string{"0.5"}})
+
+	assert.Len(histo, 2)
+	assert.Len(histo2, 1)
+	assert.ElementsMatch([]string{
+		`round(sum(rate(my_summary_sum{app="foo"}[1m])) by (code) / sum(rate(my_summary_count{app="foo"}[1m])) by (code), 0.001) > 0.001 or sum(rate(my_summary_sum{app="foo"}[1m])) by (code) / sum(rate(my_summary_count{app="foo"}[1m])) by (code)`,
+		`round(avg(my_summary{quantile="0.99",app="foo"}) by (code), 0.001) > 0.001 or avg(my_summary{quantile="0.99",app="foo"}) by (code)`,
+		`round(avg(my_summary{quantile="0.5"}), 0.001) > 0.001 or avg(my_summary{quantile="0.5"})`,
+	}, queries)
+}
+
+func TestFetchInvalidParams(t *testing.T) {
+	assert := assert.New(t)
+
+	queries := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[]}}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(extconfig.PrometheusConfig{URL: server.URL})
+	assert.Nil(err)
+
+	q := MetricsQuery{}
+	q.FillDefaults()
+	assert.Error(client.FetchRateRange(context.Background(), "my_counter", `{app="foo"}) or vector(1`, "", &q).Err)
+	assert.Error(client.FetchRateRange(context.Background(), "my_counter{}", "{}", "", &q).Err)
+	assert.Error(client.FetchRange(context.Background(), "my_gauge", "{}", "code) or (x", "sum", &q).Err)
+	assert.Error(client.FetchRange(context.Background(), "my_gauge", "{}", "", "drop_all", &q).Err)
+	assert.Error(client.FetchExprRange(context.Background(), "sum(rate(errors$__labels[$__rate_interval])", "{}", "", &q).Err)
+
+	q.RateInterval = "5m]) or vector(1"
+	histo := client.FetchHistogramRange(context.Background(), "my_histogram", "{}", "", &q)
+	assert.Len(histo, 1)
+	for _, metric := range histo {
+		assert.Error(metric.Err)
+	}
+
+	q.RateInterval = "5m"
+	metric := client.FetchRateRange(context.Background(), "my_counter", `{app="foo"}`, "code", &q)
+	assert.Nil(metric.Err)
+	assert.Equal(`round(sum(rate(my_counter{app="foo"}[5m])) by (code), 0.001) > 0.001 or sum(rate(my_counter{app="foo"}[5m])) by (code)`, metric.Query)
+	assert.Equal(1, queries)
+}
+
+func TestFetchRounding(t *testing.T) {
+	assert := assert.New(t)
+
+	lock := sync.Mutex{}
+	queries := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		queries = append(queries, r.FormValue("query"))
+		lock.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[]}}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(extconfig.PrometheusConfig{URL: server.URL})
+	assert.Nil(err)
+
+	q := MetricsQuery{}
+	q.FillDefaults()
+	precision := 0.00001
+	q.Rounding = &precision
+	client.FetchRateRange(context.Background(), "my_counter", "{}", "", &q)
+	none := 0.0
+	q.Rounding = &none
+	client.FetchRateRange(context.Background(), "my_counter", "{}", "", &q)
+
+	assert.Equal([]string{
+		`round(sum(rate(my_counter[1m])), 0.00001) > 0.00001 or sum(rate(my_counter[1m]))`,
+		`sum(rate(my_counter[1m]))`,
+	}, queries)
+}
+
+func TestFetchInstant(t *testing.T) {
+	assert := assert.New(t)
+
+	lock := sync.Mutex{}
+	requests := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		requests = append(requests, r.URL.Path+" "+r.FormValue("time")+" "+r.FormValue("query"))
+		lock.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if r.FormValue("query") == "1 + 1" {
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"scalar","result":[1000,"2"]}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{"code":"200"},"value":[1000,"1.5"]}]}}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(extconfig.PrometheusConfig{URL: server.URL})
+	assert.Nil(err)
+
+	q := MetricsQuery{}
+	q.FillDefaults()
+	q.End = time.Unix(1000, 0)
+	q.Instant = true
+	none := 0.0
+	q.Rounding = &none
+	metric := client.FetchRateRange(context.Background(), "my_counter", "{}", "code", &q)
+	assert.Nil(metric.Err)
+	assert.Nil(metric.Matrix)
+	assert.Len(metric.Vector, 1)
+	assert.Equal("200", string(metric.Vector[0].Metric["code"]))
+	assert.Equal(1.5, float64(metric.Vector[0].Value))
+
+	scalar := client.FetchExprRange(context.Background(), "1 + 1", "{}", "", &q)
+	assert.Nil(scalar.Err)
+	assert.Len(scalar.Vector, 1)
+	assert.Equal(2.0, float64(scalar.Vector[0].Value))
+
+	assert.Equal([]string{
+		"/api/v1/query 1970-01-01T00:16:40Z sum(rate(my_counter[1m])) by (code)",
+		"/api/v1/query 1970-01-01T00:16:40Z 1 + 1",
+	}, requests)
+}
@@ -1,6 +1,8 @@
 package mock
 
 import (
+	"context"
+
 	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/mock"
 
@@ -11,26 +13,55 @@ type PromClientMock struct {
 	mock.Mock
 }
 
-func (o *PromClientMock) FetchRange(metricName, labels, grouping, aggregator string, q *prometheus.MetricsQuery) prometheus.Metric {
+func (o *PromClientMock) FetchRange(ctx context.Context, metricName, labels, grouping, aggregator string, q *prometheus.MetricsQuery) prometheus.Metric {
 	args := o.Called(metricName, labels, grouping, aggregator, q)
 	return args.Get(0).(prometheus.Metric)
 }
 
-func (o *PromClientMock) FetchRateRange(metricName, labels, grouping string, q *prometheus.MetricsQuery) prometheus.Metric {
+func (o *PromClientMock) FetchRateRange(ctx context.Context, metricName, labels, grouping string, q *prometheus.MetricsQuery) prometheus.Metric {
 	args := o.Called(metricName, labels, grouping, q)
 	return args.Get(0).(prometheus.Metric)
 }
 
-func (o *PromClientMock) FetchHistogramRange(metricName, labels, grouping string, q *prometheus.MetricsQuery) prometheus.Histogram {
+func (o *PromClientMock) FetchExprRange(ctx context.Context, expr, labels, grouping string, q *prometheus.MetricsQuery) prometheus.Metric {
+	args := o.Called(expr, labels, grouping, q)
+	return args.Get(0).(prometheus.Metric)
+}
+
+func (o *PromClientMock) FetchHistogramRange(ctx context.Context, metricName, labels, grouping string, q *prometheus.MetricsQuery) prometheus.Histogram {
 	args := o.Called(metricName, labels, grouping, q)
 	return args.Get(0).(prometheus.Histogram)
 }
 
-func (o *PromClientMock) GetMetricsForLabels(labels []string) ([]string, error) {
+func (o *PromClientMock) FetchNativeHistogramRange(ctx context.Context, metricName, labels, grouping string, q *prometheus.MetricsQuery) prometheus.Histogram {
+	args := o.Called(metricName, labels, grouping, q)
+	return args.Get(0).(prometheus.Histogram)
+}
+
+func (o *PromClientMock) FetchHistogramBuckets(ctx context.Context, metricName, labels, grouping string, q *prometheus.MetricsQuery) prometheus.Metric {
+	args := o.Called(metricName, labels, grouping, q)
+	return args.Get(0).(prometheus.Metric)
+}
+
+func (o *PromClientMock) FetchSummaryRange(ctx context.Context, metricName, labels, grouping string, q *prometheus.MetricsQuery) prometheus.Histogram {
+	args := o.Called(metricName, labels, grouping, q)
+	return args.Get(0).(prometheus.Histogram)
+}
+
+func (o *PromClientMock) GetMetricsForLabels(ctx context.Context, labels []string) ([]string, error) {
 	args := o.Called(labels)
 	return args.Get(0).([]string), args.Error(1)
 }
 
+func (o *PromClientMock) GetLabelValues(ctx context.Context, label string, series []string) ([]string, error) {
+	args := o.Called(label, series)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (o *PromClientMock) ForNamespace(namespace string) prometheus.ClientInterface {
+	return o
+}
+
 func FakeCounter(value int) prometheus.Metric {
 	return prometheus.Metric{
 		Matrix: model.Matrix{
@@ -0,0 +1,51 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// DefaultMaxConcurrentQueries is the default cap of concurrent queries to a Prometheus server
+const DefaultMaxConcurrentQueries = 20
+
+var (
+	pools     = make(map[string]*queryPool)
+	poolsLock sync.Mutex
+)
+
+// queryPool bounds the number of concurrent queries to a Prometheus server.
+// It is shared by all the clients to the same server, hence across concurrent dashboard requests.
+type queryPool struct {
+	slots chan struct{}
+}
+
+// getQueryPool returns the pool shared for the given server URL and size, creating it on first call
+func getQueryPool(url string, size int) *queryPool {
+	if size <= 0 {
+		size = DefaultMaxConcurrentQueries
+	}
+	key := fmt.Sprintf("%s|%d", url, size)
+	poolsLock.Lock()
+	defer poolsLock.Unlock()
+	if pool, ok := pools[key]; ok {
+		return pool
+	}
+	pool := &queryPool{slots: make(chan struct{}, size)}
+	pools[key] = pool
+	return pool
+}
+
+// acquire waits for a free slot. It returns an error if the context is done first.
+func (p *queryPool) acquire(ctx context.Context) error {
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *queryPool) release() {
+	<-p.slots
+}
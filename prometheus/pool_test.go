@@ -0,0 +1,66 @@
+package prometheus
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiali/k-charted/config/extconfig"
+)
+
+func TestQueryPool(t *testing.T) {
+	assert := assert.New(t)
+
+	pool := getQueryPool("http://pool-test", 1)
+	assert.True(pool == getQueryPool("http://pool-test", 1))
+	assert.False(pool == getQueryPool("http://pool-test", 2))
+	assert.Equal(DefaultMaxConcurrentQueries, cap(getQueryPool("http://pool-test", 0).slots))
+
+	assert.Nil(pool.acquire(context.Background()))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.Equal(context.DeadlineExceeded, pool.acquire(ctx))
+	pool.release()
+	assert.Nil(pool.acquire(context.Background()))
+	pool.release()
+}
+
+func TestHistogramQueriesBounded(t *testing.T) {
+	assert := assert.New(t)
+
+	lock := sync.Mutex{}
+	running, maxRunning, total := 0, 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		running++
+		total++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		lock.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		lock.Lock()
+		running--
+		lock.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[]}}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(extconfig.PrometheusConfig{URL: server.URL, MaxConcurrentQueries: 2})
+	assert.Nil(err)
+
+	q := MetricsQuery{}
+	q.FillDefaults()
+	q.Quantiles = []string{"0.5", "0.9", "0.95", "0.99"}
+	histo := client.FetchHistogramRange(context.Background(), "my_histogram", "{}", "", &q)
+
+	assert.Len(histo, 5)
+	assert.Equal(5, total)
+	assert.Equal(2, maxRunning)
+}
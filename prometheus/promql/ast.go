@@ -0,0 +1,239 @@
+// Package promql builds, parses and validates PromQL expressions, so that queries are checked before being sent.
+// It covers the PromQL syntax, including subqueries and the offset and @ modifiers, with a lighter semantic check than Prometheus:
+// types of function arguments aren't checked, and calls to unknown functions are passed through, with their arguments parsed.
+// The upstream Prometheus parser can't be used, as its module conflicts with the Kubernetes client version of this project.
+package promql
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Expr is a node of a PromQL expression. String renders it as PromQL.
+type Expr interface {
+	String() string
+}
+
+// NumberLiteral is a scalar number. Text keeps the original notation of parsed numbers.
+type NumberLiteral struct {
+	Value float64
+	Text  string
+}
+
+// StringLiteral is a string
+type StringLiteral struct {
+	Value string
+}
+
+// VectorSelector selects series by metric name and label matchers
+type VectorSelector struct {
+	Name     string
+	Matchers Selector
+	// Offset and At are the optional "offset" and "@" modifiers, as written
+	Offset string
+	At     string
+}
+
+// MatrixSelector selects a range of samples of series
+type MatrixSelector struct {
+	Vector *VectorSelector
+	Range  string
+}
+
+// SubqueryExpr evaluates an expression over a range, e.g. rate(foo[5m])[1h:1m]
+type SubqueryExpr struct {
+	Expr   Expr
+	Range  string
+	Step   string
+	Offset string
+	At     string
+}
+
+// Call is a function call
+type Call struct {
+	Func string
+	Args []Expr
+}
+
+// AggregateExpr is an aggregation, e.g. sum(foo) by (bar). Param is set for parameterized aggregations, such as topk.
+type AggregateExpr struct {
+	Op       string
+	Expr     Expr
+	Param    Expr
+	Grouping []string
+	Without  bool
+}
+
+// BinaryExpr is a binary operation. ReturnBool is the "bool" modifier of comparisons.
+type BinaryExpr struct {
+	Op         string
+	LHS, RHS   Expr
+	ReturnBool bool
+	Matching   *VectorMatching
+}
+
+// VectorMatching holds the vector matching modifiers of a binary operation: on or ignoring, and group_left or group_right
+type VectorMatching struct {
+	On      bool
+	Labels  []string
+	Card    string
+	Include []string
+}
+
+// ParenExpr is an expression within parentheses
+type ParenExpr struct {
+	Expr Expr
+}
+
+// UnaryExpr is a negated (or explicitly positive) expression
+type UnaryExpr struct {
+	Op   string
+	Expr Expr
+}
+
+// Number returns a number literal
+func Number(value float64) *NumberLiteral {
+	return &NumberLiteral{Value: value}
+}
+
+// Metric returns a vector selector
+func Metric(name string, matchers Selector) *VectorSelector {
+	return &VectorSelector{Name: name, Matchers: matchers}
+}
+
+// Range returns a matrix selector over the given vector selector
+func Range(vector *VectorSelector, rng string) *MatrixSelector {
+	return &MatrixSelector{Vector: vector, Range: rng}
+}
+
+// Func returns a function call
+func Func(name string, args ...Expr) *Call {
+	return &Call{Func: name, Args: args}
+}
+
+// Aggregate returns an aggregation, grouped by the given labels
+func Aggregate(op string, expr Expr, by []string) *AggregateExpr {
+	return &AggregateExpr{Op: op, Expr: expr, Grouping: by}
+}
+
+// Binary returns a binary operation
+func Binary(op string, lhs, rhs Expr) *BinaryExpr {
+	return &BinaryExpr{Op: op, LHS: lhs, RHS: rhs}
+}
+
+func (n *NumberLiteral) String() string {
+	if n.Text != "" {
+		return n.Text
+	}
+	return strconv.FormatFloat(n.Value, 'f', -1, 64)
+}
+
+func (s *StringLiteral) String() string {
+	return strconv.Quote(s.Value)
+}
+
+func (v *VectorSelector) String() string {
+	return v.selector() + modifiers(v.Offset, v.At)
+}
+
+func (v *VectorSelector) selector() string {
+	if v.Name != "" && len(v.Matchers) == 0 {
+		return v.Name
+	}
+	return v.Name + v.Matchers.String()
+}
+
+func (m *MatrixSelector) String() string {
+	return m.Vector.selector() + "[" + m.Range + "]" + modifiers(m.Vector.Offset, m.Vector.At)
+}
+
+func (s *SubqueryExpr) String() string {
+	return s.Expr.String() + "[" + s.Range + ":" + s.Step + "]" + modifiers(s.Offset, s.At)
+}
+
+func modifiers(offset, at string) string {
+	s := ""
+	if offset != "" {
+		s += " offset " + offset
+	}
+	if at != "" {
+		s += " @ " + at
+	}
+	return s
+}
+
+func (c *Call) String() string {
+	args := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		args[i] = arg.String()
+	}
+	return c.Func + "(" + strings.Join(args, ", ") + ")"
+}
+
+func (a *AggregateExpr) String() string {
+	s := a.Op + "("
+	if a.Param != nil {
+		s += a.Param.String() + ", "
+	}
+	s += a.Expr.String() + ")"
+	if a.Without {
+		s += " without (" + strings.Join(a.Grouping, ",") + ")"
+	} else if len(a.Grouping) > 0 {
+		s += " by (" + strings.Join(a.Grouping, ",") + ")"
+	}
+	return s
+}
+
+func (b *BinaryExpr) String() string {
+	op := b.Op
+	if b.ReturnBool {
+		op += " bool"
+	}
+	if m := b.Matching; m != nil {
+		if m.On {
+			op += " on (" + strings.Join(m.Labels, ",") + ")"
+		} else if len(m.Labels) > 0 {
+			op += " ignoring (" + strings.Join(m.Labels, ",") + ")"
+		}
+		if m.Card != "" {
+			op += " " + m.Card
+		}
+		if len(m.Include) > 0 {
+			op += " (" + strings.Join(m.Include, ",") + ")"
+		}
+	}
+	prec := precedence[b.Op]
+	lhs, rhs := b.LHS.String(), b.RHS.String()
+	// Built expressions have no explicit parentheses: add them where precedence requires it
+	if needsParens(b.LHS, prec, b.Op == "^") {
+		lhs = "(" + lhs + ")"
+	}
+	if needsParens(b.RHS, prec, b.Op != "^") {
+		rhs = "(" + rhs + ")"
+	}
+	return lhs + " " + op + " " + rhs
+}
+
+// needsParens tells whether an operand of an operator with the given precedence must be wrapped in parentheses.
+// Operands with the same precedence need them on the side opposite to associativity.
+func needsParens(operand Expr, prec int, sameNeedsParens bool) bool {
+	switch o := operand.(type) {
+	case *BinaryExpr:
+		return precedence[o.Op] < prec || (precedence[o.Op] == prec && sameNeedsParens)
+	case *UnaryExpr:
+		// Unary operators bind less tightly than ^
+		return prec == precedence["^"]
+	}
+	return false
+}
+
+func (p *ParenExpr) String() string {
+	return "(" + p.Expr.String() + ")"
+}
+
+func (u *UnaryExpr) String() string {
+	if b, ok := u.Expr.(*BinaryExpr); ok && b.Op != "^" {
+		return u.Op + "(" + u.Expr.String() + ")"
+	}
+	return u.Op + u.Expr.String()
+}
@@ -0,0 +1,23 @@
+package promql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ExpandExpr replaces placeholders in a PromQL expression template:
+// $__labels with the label matchers, including braces; $__matchers with the label matchers, without braces;
+// $__by with the grouping clause, or nothing when there's no grouping; $__rate_interval with the rate interval.
+// Example: sum(rate(my_counter$__labels[$__rate_interval]))$__by
+func ExpandExpr(expr, labels, grouping, rateInterval string) string {
+	by := ""
+	if grouping != "" {
+		by = fmt.Sprintf(" by (%s)", grouping)
+	}
+	return strings.NewReplacer(
+		"$__labels", labels,
+		"$__matchers", strings.TrimSuffix(strings.TrimPrefix(labels, "{"), "}"),
+		"$__by", by,
+		"$__rate_interval", rateInterval,
+	).Replace(expr)
+}
@@ -0,0 +1,17 @@
+package promql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandExpr(t *testing.T) {
+	assert := assert.New(t)
+
+	labels := `{namespace="ns",app="foo"}`
+	assert.Equal(`sum(rate(errors{namespace="ns",app="foo"}[5m])) by (code) / sum(rate(total{namespace="ns",app="foo"}[5m])) by (code)`,
+		ExpandExpr("sum(rate(errors$__labels[$__rate_interval]))$__by / sum(rate(total$__labels[$__rate_interval]))$__by", labels, "code", "5m"))
+	assert.Equal(`sum(increase(errors{code=~"5..",namespace="ns",app="foo"}[1m]))`,
+		ExpandExpr(`sum(increase(errors{code=~"5..",$__matchers}[$__rate_interval]))$__by`, labels, "", "1m"))
+}
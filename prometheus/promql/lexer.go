@@ -0,0 +1,208 @@
+package promql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokDuration
+	tokString
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// pos is the byte offset of the token in the input
+	pos int
+}
+
+// operators are sorted so that longer operators are matched first
+var operators = []string{"==", "!=", ">=", "<=", "=~", "!~", "+", "-", "*", "/", "%", "^", ">", "<", "=", ",", "(", ")", "{", "}", "[", "]", ":", "@"}
+
+const durationUnits = "smhdwy"
+
+func lex(input string) ([]token, error) {
+	tokens := []token{}
+	// Within brackets, ":" separates the range and step of subqueries rather than starting a metric name
+	inBrackets := false
+	for pos := 0; pos < len(input); {
+		c := input[pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			pos++
+		case c == '#':
+			for pos < len(input) && input[pos] != '\n' {
+				pos++
+			}
+		case isIdentStart(c) && !(c == ':' && inBrackets):
+			end := pos + 1
+			for end < len(input) && isIdentChar(input[end]) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: input[pos:end], pos: pos})
+			pos = end
+		case isDigit(c) || (c == '.' && pos+1 < len(input) && isDigit(input[pos+1])):
+			tok, err := lexNumber(input, pos)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, tok)
+			pos += len(tok.text)
+		case c == '"' || c == '\'' || c == '`':
+			tok, err := lexString(input, pos)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, tok)
+			pos += stringLength(input[pos:])
+		default:
+			op := ""
+			for _, candidate := range operators {
+				if strings.HasPrefix(input[pos:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, pos+1)
+			}
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: pos})
+			pos += len(op)
+			if op == "[" || op == "]" {
+				inBrackets = op == "["
+			}
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(input)}), nil
+}
+
+// lexNumber reads a number, or a duration such as 5m or 1h30m
+func lexNumber(input string, pos int) (token, error) {
+	end := pos
+	if strings.HasPrefix(input[pos:], "0x") || strings.HasPrefix(input[pos:], "0X") {
+		end += 2
+		for end < len(input) && strings.IndexByte("0123456789abcdefABCDEF", input[end]) >= 0 {
+			end++
+		}
+		return token{kind: tokNumber, text: input[pos:end], pos: pos}, nil
+	}
+	for end < len(input) && isDigit(input[end]) {
+		end++
+	}
+	if end < len(input) && strings.IndexByte(durationUnits, input[end]) >= 0 {
+		return lexDuration(input, pos)
+	}
+	if end < len(input) && input[end] == '.' {
+		end++
+		for end < len(input) && isDigit(input[end]) {
+			end++
+		}
+	}
+	if end < len(input) && (input[end] == 'e' || input[end] == 'E') {
+		exp := end + 1
+		if exp < len(input) && (input[exp] == '+' || input[exp] == '-') {
+			exp++
+		}
+		if exp < len(input) && isDigit(input[exp]) {
+			end = exp
+			for end < len(input) && isDigit(input[end]) {
+				end++
+			}
+		}
+	}
+	if end < len(input) && isIdentChar(input[end]) && input[end] != ':' {
+		return token{}, fmt.Errorf("bad number or duration syntax at position %d", pos+1)
+	}
+	return token{kind: tokNumber, text: input[pos:end], pos: pos}, nil
+}
+
+func lexDuration(input string, pos int) (token, error) {
+	end := pos
+	for end < len(input) && isDigit(input[end]) {
+		for end < len(input) && isDigit(input[end]) {
+			end++
+		}
+		if strings.HasPrefix(input[end:], "ms") {
+			end += 2
+		} else if end < len(input) && strings.IndexByte(durationUnits, input[end]) >= 0 {
+			end++
+		} else {
+			return token{}, fmt.Errorf("bad duration syntax at position %d", pos+1)
+		}
+	}
+	if end < len(input) && isIdentChar(input[end]) && input[end] != ':' {
+		return token{}, fmt.Errorf("bad duration syntax at position %d", pos+1)
+	}
+	return token{kind: tokDuration, text: input[pos:end], pos: pos}, nil
+}
+
+// lexString reads a quoted string; the token text is the unquoted value
+func lexString(input string, pos int) (token, error) {
+	n := stringLength(input[pos:])
+	if n < 0 {
+		return token{}, fmt.Errorf("unterminated string at position %d", pos+1)
+	}
+	quote, body := input[pos], input[pos+1:pos+n-1]
+	if quote == '`' {
+		return token{kind: tokString, text: body, pos: pos}, nil
+	}
+	if quote == '\'' {
+		// Convert to a double-quoted string, for unquoting
+		var b strings.Builder
+		for i := 0; i < len(body); i++ {
+			if body[i] == '\\' && i+1 < len(body) && body[i+1] == '\'' {
+				b.WriteByte('\'')
+				i++
+			} else if body[i] == '\\' && i+1 < len(body) {
+				b.WriteString(body[i : i+2])
+				i++
+			} else if body[i] == '"' {
+				b.WriteString(`\"`)
+			} else {
+				b.WriteByte(body[i])
+			}
+		}
+		body = b.String()
+	}
+	value, err := strconv.Unquote(`"` + body + `"`)
+	if err != nil {
+		return token{}, fmt.Errorf("invalid string at position %d: %v", pos+1, err)
+	}
+	return token{kind: tokString, text: value, pos: pos}, nil
+}
+
+// stringLength returns the length of the quoted string s starts with, quotes included, or -1 when unterminated
+func stringLength(s string) int {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quote != '`':
+			i++
+		case s[i] == quote:
+			return i + 1
+		case s[i] == '\n' && quote != '`':
+			return -1
+		}
+	}
+	return -1
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c == ':' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
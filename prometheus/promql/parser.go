@@ -0,0 +1,511 @@
+package promql
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/common/model"
+)
+
+var (
+	precedence = map[string]int{
+		"or":     1,
+		"and":    2,
+		"unless": 2,
+		"==":     3,
+		"!=":     3,
+		"<=":     3,
+		"<":      3,
+		">=":     3,
+		">":      3,
+		"+":      4,
+		"-":      4,
+		"*":      5,
+		"/":      5,
+		"%":      5,
+		"atan2":  5,
+		"^":      6,
+	}
+	comparisons = map[string]bool{"==": true, "!=": true, "<=": true, "<": true, ">=": true, ">": true}
+
+	aggregations = map[string]bool{
+		"sum": true, "avg": true, "count": true, "min": true, "max": true, "group": true, "stddev": true, "stdvar": true,
+		"topk": true, "bottomk": true, "quantile": true, "count_values": true, "limitk": true, "limit_ratio": true,
+	}
+	parameterized = map[string]bool{"topk": true, "bottomk": true, "quantile": true, "count_values": true, "limitk": true, "limit_ratio": true}
+
+	// functions are the known PromQL functions with their minimum and maximum number of arguments (-1: no maximum).
+	// Calls to other functions, such as experimental ones or ones added by later Prometheus versions, are passed through unchecked.
+	// See https://prometheus.io/docs/prometheus/latest/querying/functions/
+	functions = map[string][2]int{
+		"abs": {1, 1}, "absent": {1, 1}, "absent_over_time": {1, 1}, "acos": {1, 1}, "acosh": {1, 1}, "asin": {1, 1},
+		"asinh": {1, 1}, "atan": {1, 1}, "atanh": {1, 1}, "avg_over_time": {1, 1}, "ceil": {1, 1}, "changes": {1, 1},
+		"clamp": {3, 3}, "clamp_max": {2, 2}, "clamp_min": {2, 2}, "cos": {1, 1}, "cosh": {1, 1}, "count_over_time": {1, 1},
+		"day_of_month": {0, 1}, "day_of_week": {0, 1}, "day_of_year": {0, 1}, "days_in_month": {0, 1}, "deg": {1, 1},
+		"delta": {1, 1}, "deriv": {1, 1}, "double_exponential_smoothing": {3, 3}, "exp": {1, 1}, "floor": {1, 1}, "histogram_avg": {1, 1}, "histogram_count": {1, 1},
+		"histogram_fraction": {3, 3}, "histogram_quantile": {2, 2}, "histogram_stddev": {1, 1}, "histogram_stdvar": {1, 1},
+		"histogram_sum": {1, 1}, "holt_winters": {3, 3}, "hour": {0, 1}, "idelta": {1, 1}, "increase": {1, 1}, "info": {1, 2}, "irate": {1, 1}, "label_join": {3, -1},
+		"label_replace": {5, 5}, "last_over_time": {1, 1}, "ln": {1, 1}, "log10": {1, 1}, "log2": {1, 1}, "mad_over_time": {1, 1},
+		"max_over_time": {1, 1}, "min_over_time": {1, 1}, "minute": {0, 1}, "month": {0, 1}, "pi": {0, 0},
+		"predict_linear": {2, 2}, "present_over_time": {1, 1}, "quantile_over_time": {2, 2}, "rad": {1, 1}, "rate": {1, 1},
+		"resets": {1, 1}, "round": {1, 2}, "scalar": {1, 1}, "sgn": {1, 1}, "sin": {1, 1}, "sinh": {1, 1}, "sort": {1, 1},
+		"sort_by_label": {1, -1}, "sort_by_label_desc": {1, -1}, "sort_desc": {1, 1}, "sqrt": {1, 1}, "stddev_over_time": {1, 1}, "stdvar_over_time": {1, 1}, "sum_over_time": {1, 1},
+		"tan": {1, 1}, "tanh": {1, 1}, "time": {0, 0}, "timestamp": {1, 1}, "vector": {1, 1}, "year": {0, 1},
+	}
+
+	keywords = toSet("and", "or", "unless", "atan2", "bool", "on", "ignoring", "group_left", "group_right", "by", "without", "offset")
+)
+
+func toSet(values ...string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// parseError is raised by the parser through panics, and recovered in the Parse functions
+type parseError struct {
+	err error
+}
+
+// ParseExpr parses and checks a PromQL expression
+func ParseExpr(input string) (expr Expr, err error) {
+	p, err := newParser(input)
+	if err != nil {
+		return nil, err
+	}
+	defer p.recover(&err)
+	expr = p.parseExpr()
+	p.expect(tokEOF, "")
+	return expr, nil
+}
+
+// ParseSelector parses label matchers within braces, such as {app="foo",version!="v1"}. An empty input is an empty selector.
+func ParseSelector(input string) (selector Selector, err error) {
+	if strings.TrimSpace(input) == "" {
+		return Selector{}, nil
+	}
+	p, err := newParser(input)
+	if err != nil {
+		return nil, err
+	}
+	defer p.recover(&err)
+	selector = p.parseMatchers()
+	p.expect(tokEOF, "")
+	return selector, nil
+}
+
+// IsDuration tells whether s is a PromQL duration, such as 5m or 1h30m
+func IsDuration(s string) bool {
+	tok, err := lexDuration(s, 0)
+	return err == nil && s != "" && tok.text == s
+}
+
+// IsFunction tells whether name is a known PromQL function
+func IsFunction(name string) bool {
+	_, ok := functions[name]
+	return ok
+}
+
+// IsAggregation tells whether op is a PromQL aggregation operator
+func IsAggregation(op string) bool {
+	return aggregations[op]
+}
+
+// Check checks the syntax of a PromQL query
+func Check(query string) error {
+	_, err := ParseExpr(query)
+	return err
+}
+
+func newParser(input string) (*parser, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PromQL: %v", err)
+	}
+	return &parser{tokens: tokens}, nil
+}
+
+func (p *parser) recover(err *error) {
+	if r := recover(); r != nil {
+		perr, ok := r.(parseError)
+		if !ok {
+			panic(r)
+		}
+		*err = perr.err
+	}
+}
+
+func (p *parser) fail(format string, args ...interface{}) {
+	tok := p.peek()
+	panic(parseError{err: fmt.Errorf("invalid PromQL at position %d: %s", tok.pos+1, fmt.Sprintf(format, args...))})
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) peekAt(offset int) token {
+	if p.pos+offset >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.pos+offset]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) is(kind tokenKind, text string) bool {
+	tok := p.peek()
+	return tok.kind == kind && tok.text == text
+}
+
+// expect consumes a token of the given kind, and text unless empty
+func (p *parser) expect(kind tokenKind, text string) token {
+	tok := p.peek()
+	if tok.kind != kind || (text != "" && tok.text != text) {
+		if tok.kind == tokEOF {
+			p.fail("unexpected end of input")
+		}
+		p.fail("unexpected %q", tok.text)
+	}
+	return p.next()
+}
+
+func (p *parser) parseExpr() Expr {
+	return p.parseBinary(1)
+}
+
+// parseBinary parses binary operations by precedence climbing, with operators of at least the given precedence
+func (p *parser) parseBinary(minPrec int) Expr {
+	lhs := p.parseUnary()
+	for {
+		tok := p.peek()
+		prec, ok := precedence[tok.text]
+		if !ok || (tok.kind != tokOp && tok.kind != tokIdent) || prec < minPrec {
+			return lhs
+		}
+		p.next()
+		bin := &BinaryExpr{Op: tok.text, LHS: lhs}
+		if p.is(tokIdent, "bool") {
+			if !comparisons[bin.Op] {
+				p.fail("bool modifier can only be used on comparison operators")
+			}
+			p.next()
+			bin.ReturnBool = true
+		}
+		bin.Matching = p.parseVectorMatching()
+		if bin.Matching != nil && (bin.Op == "and" || bin.Op == "or" || bin.Op == "unless") && bin.Matching.Card != "" {
+			p.fail("no grouping allowed for %q operation", bin.Op)
+		}
+		if bin.Op == "^" {
+			// Right-associative
+			bin.RHS = p.parseBinary(prec)
+		} else {
+			bin.RHS = p.parseBinary(prec + 1)
+		}
+		lhs = bin
+	}
+}
+
+func (p *parser) parseVectorMatching() *VectorMatching {
+	var matching *VectorMatching
+	if p.is(tokIdent, "on") || p.is(tokIdent, "ignoring") {
+		matching = &VectorMatching{On: p.next().text == "on"}
+		matching.Labels = p.parseLabelList()
+	}
+	if p.is(tokIdent, "group_left") || p.is(tokIdent, "group_right") {
+		if matching == nil {
+			p.fail("group modifiers require on or ignoring")
+		}
+		matching.Card = p.next().text
+		if p.is(tokOp, "(") {
+			matching.Include = p.parseLabelList()
+		}
+	}
+	return matching
+}
+
+func (p *parser) parseUnary() Expr {
+	if p.is(tokOp, "-") || p.is(tokOp, "+") {
+		op := p.next().text
+		// Unary operators bind less tightly than ^
+		return &UnaryExpr{Op: op, Expr: p.parseBinary(precedence["^"])}
+	}
+	return p.parsePostfix(p.parsePrimary())
+}
+
+// parsePostfix parses ranges, subqueries, offset and @ modifiers
+func (p *parser) parsePostfix(expr Expr) Expr {
+	for {
+		switch {
+		case p.is(tokOp, "["):
+			p.next()
+			rng := p.parseDuration()
+			if p.is(tokOp, ":") {
+				p.next()
+				step := ""
+				if !p.is(tokOp, "]") {
+					step = p.parseDuration()
+				}
+				p.expect(tokOp, "]")
+				expr = &SubqueryExpr{Expr: expr, Range: rng, Step: step}
+				continue
+			}
+			p.expect(tokOp, "]")
+			vector, ok := expr.(*VectorSelector)
+			if !ok || vector.Offset != "" || vector.At != "" {
+				p.fail("ranges are only allowed for vector selectors")
+			}
+			expr = &MatrixSelector{Vector: vector, Range: rng}
+		case p.is(tokIdent, "offset"):
+			p.next()
+			offset := ""
+			if p.is(tokOp, "-") {
+				p.next()
+				offset = "-"
+			}
+			offset += p.parseDuration()
+			p.setModifier(expr, "offset", offset)
+		case p.is(tokOp, "@"):
+			p.next()
+			p.setModifier(expr, "@", p.parseAt())
+		default:
+			return expr
+		}
+	}
+}
+
+// setModifier sets the offset or @ modifier of a selector or subquery
+func (p *parser) setModifier(expr Expr, modifier, value string) {
+	var offset, at *string
+	switch e := expr.(type) {
+	case *VectorSelector:
+		offset, at = &e.Offset, &e.At
+	case *MatrixSelector:
+		offset, at = &e.Vector.Offset, &e.Vector.At
+	case *SubqueryExpr:
+		offset, at = &e.Offset, &e.At
+	default:
+		p.fail("offset and @ modifiers are only allowed for selectors and subqueries")
+	}
+	target := offset
+	if modifier == "@" {
+		target = at
+	}
+	if *target != "" {
+		p.fail("%s may not be set multiple times", modifier)
+	}
+	*target = value
+}
+
+func (p *parser) parseAt() string {
+	tok := p.peek()
+	switch {
+	case tok.kind == tokNumber:
+		return p.next().text
+	case tok.kind == tokOp && (tok.text == "-" || tok.text == "+") && p.peekAt(1).kind == tokNumber:
+		p.next()
+		return tok.text + p.next().text
+	case tok.kind == tokIdent && (tok.text == "start" || tok.text == "end"):
+		p.next()
+		p.expect(tokOp, "(")
+		p.expect(tokOp, ")")
+		return tok.text + "()"
+	}
+	p.fail("unexpected %q in @ modifier", tok.text)
+	return ""
+}
+
+// parseDuration parses a duration, or a number of seconds
+func (p *parser) parseDuration() string {
+	tok := p.peek()
+	if tok.kind != tokDuration && tok.kind != tokNumber {
+		p.fail("duration expected, got %q", tok.text)
+	}
+	return p.next().text
+}
+
+func (p *parser) parsePrimary() Expr {
+	tok := p.peek()
+	switch tok.kind {
+	case tokNumber:
+		p.next()
+		value, err := parseNumber(tok.text)
+		if err != nil {
+			p.fail("invalid number %q", tok.text)
+		}
+		return &NumberLiteral{Value: value, Text: tok.text}
+	case tokString:
+		p.next()
+		return &StringLiteral{Value: tok.text}
+	case tokOp:
+		switch tok.text {
+		case "(":
+			p.next()
+			expr := p.parseExpr()
+			p.expect(tokOp, ")")
+			return &ParenExpr{Expr: expr}
+		case "{":
+			return p.checkSelector(&VectorSelector{Matchers: p.parseMatchers()})
+		}
+	case tokIdent:
+		next := p.peekAt(1)
+		switch {
+		case aggregations[tok.text] && (next.text == "(" || next.text == "by" || next.text == "without"):
+			return p.parseAggregate()
+		case keywords[tok.text]:
+			p.fail("unexpected keyword %q", tok.text)
+		case next.kind == tokOp && next.text == "(":
+			return p.parseCall()
+		case strings.EqualFold(tok.text, "Inf") || strings.EqualFold(tok.text, "NaN"):
+			p.next()
+			value, _ := strconv.ParseFloat(tok.text, 64)
+			return &NumberLiteral{Value: value, Text: tok.text}
+		}
+		p.next()
+		vector := &VectorSelector{Name: tok.text}
+		if p.is(tokOp, "{") {
+			vector.Matchers = p.parseMatchers()
+		}
+		return p.checkSelector(vector)
+	case tokEOF:
+		p.fail("unexpected end of input")
+	}
+	p.fail("unexpected %q", tok.text)
+	return nil
+}
+
+func parseNumber(text string) (float64, error) {
+	if strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0X") {
+		value, err := strconv.ParseInt(text, 0, 64)
+		return float64(value), err
+	}
+	return strconv.ParseFloat(text, 64)
+}
+
+// checkSelector checks that a selector doesn't match every series, as Prometheus rejects it
+func (p *parser) checkSelector(vector *VectorSelector) Expr {
+	if vector.Name != "" {
+		return vector
+	}
+	for _, m := range vector.Matchers {
+		if !m.matchesEmpty() {
+			return vector
+		}
+	}
+	p.fail("vector selector must contain at least one non-empty matcher")
+	return nil
+}
+
+func (p *parser) parseMatchers() Selector {
+	p.expect(tokOp, "{")
+	selector := Selector{}
+	for !p.is(tokOp, "}") {
+		name := p.expect(tokIdent, "")
+		op := p.peek()
+		if op.kind != tokOp || (op.text != "=" && op.text != "!=" && op.text != "=~" && op.text != "!~") {
+			p.fail("unexpected %q in label matching, expected one of =, !=, =~, !~", op.text)
+		}
+		p.next()
+		value := p.expect(tokString, "")
+		matcher, err := NewLabelMatcher(name.text, MatchType(op.text), value.text)
+		if err != nil {
+			p.fail("%v", err)
+		}
+		selector = append(selector, matcher)
+		if !p.is(tokOp, "}") {
+			p.expect(tokOp, ",")
+		}
+	}
+	p.next()
+	return selector
+}
+
+func (p *parser) parseLabelList() []string {
+	p.expect(tokOp, "(")
+	labels := []string{}
+	for !p.is(tokOp, ")") {
+		label := p.expect(tokIdent, "")
+		if !model.LabelName(label.text).IsValid() {
+			p.fail("invalid label name %q", label.text)
+		}
+		labels = append(labels, label.text)
+		if !p.is(tokOp, ")") {
+			p.expect(tokOp, ",")
+		}
+	}
+	p.next()
+	return labels
+}
+
+func (p *parser) parseGrouping(agg *AggregateExpr) {
+	agg.Without = p.next().text == "without"
+	agg.Grouping = p.parseLabelList()
+}
+
+func (p *parser) parseAggregate() Expr {
+	agg := &AggregateExpr{Op: p.next().text}
+	grouped := false
+	if p.is(tokIdent, "by") || p.is(tokIdent, "without") {
+		p.parseGrouping(agg)
+		grouped = true
+	}
+	p.expect(tokOp, "(")
+	if parameterized[agg.Op] {
+		agg.Param = p.parseExpr()
+		p.expect(tokOp, ",")
+	}
+	agg.Expr = p.parseExpr()
+	p.expect(tokOp, ")")
+	if !grouped && (p.is(tokIdent, "by") || p.is(tokIdent, "without")) {
+		p.parseGrouping(agg)
+	}
+	return agg
+}
+
+func (p *parser) parseCall() Expr {
+	name := p.next()
+	call := &Call{Func: name.text, Args: []Expr{}}
+	p.expect(tokOp, "(")
+	for !p.is(tokOp, ")") {
+		call.Args = append(call.Args, p.parseExpr())
+		if !p.is(tokOp, ")") {
+			p.expect(tokOp, ",")
+		}
+	}
+	// Unknown functions are passed through, so that newer Prometheus functions can be used
+	if arity, ok := functions[call.Func]; ok && (len(call.Args) < arity[0] || (arity[1] >= 0 && len(call.Args) > arity[1])) {
+		p.fail("wrong number of arguments for function %q: %d", call.Func, len(call.Args))
+	}
+	p.next()
+	return call
+}
+
+// matchesEmpty tells whether the matcher matches series without the label
+func (m LabelMatcher) matchesEmpty() bool {
+	switch m.Type {
+	case MatchEqual:
+		return m.Value == ""
+	case MatchNotEqual:
+		return m.Value != ""
+	}
+	re, err := regexp.Compile("^(?:" + m.Value + ")$")
+	if err != nil {
+		return false
+	}
+	return re.MatchString("") == (m.Type == MatchRegexp)
+}
@@ -0,0 +1,144 @@
+package promql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseExprRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, query := range []string{
+		`up`,
+		`up{job="prometheus",instance!~"localhost.*"}`,
+		`sum(rate(http_requests_total{code=~"5.."}[5m])) by (app) / sum(rate(http_requests_total[5m])) by (app)`,
+		`histogram_quantile(0.99, sum(rate(http_request_duration_seconds_bucket[1m])) by (le,app))`,
+		`round(sum(rate(foo[1m])), 0.001) > 0.001 or sum(rate(foo[1m]))`,
+		`topk(5, sum(foo) without (instance))`,
+		`count_values("version", build_info)`,
+		`foo / on (instance) group_left (version) bar`,
+		`foo > bool 1`,
+		`-foo ^ 2`,
+		`(1 + 2) * 3`,
+		`sum_over_time(foo[5m] offset 1h)`,
+		`foo offset 1d - foo`,
+		`max_over_time(rate(foo[5m])[1h:1m])`,
+		`foo[5m:1m]`,
+		`rate(foo[5m])[1h:] offset 1d`,
+		`foo[5m] offset -1h`,
+		`foo @ 1609746000`,
+		`rate(foo[5m] @ end())`,
+		`foo / ignoring (le) group_left sum(bar)`,
+		`foo * on (instance) group_right bar`,
+		`limitk(2, foo)`,
+		`info(up, {k8s_cluster_name=~".+"})`,
+		`some_future_function(foo, "x", 1)`,
+		`label_replace(up, "dst", "$1", "src", "(.*)")`,
+		`foo atan2 bar`,
+		`{__name__=~"foo.*"}`,
+		`foo unless bar and baz`,
+	} {
+		expr, err := ParseExpr(query)
+		if assert.Nil(err, query) {
+			assert.Equal(query, expr.String())
+		}
+	}
+}
+
+func TestParseExprStructure(t *testing.T) {
+	assert := assert.New(t)
+
+	expr, err := ParseExpr(`sum by (app) (rate(foo{a='x"y'}[5m])) * 2 + 1`)
+	assert.Nil(err)
+	assert.Equal(`sum(rate(foo{a="x\"y"}[5m])) by (app) * 2 + 1`, expr.String())
+	add := expr.(*BinaryExpr)
+	assert.Equal("+", add.Op)
+	assert.Equal("*", add.LHS.(*BinaryExpr).Op)
+
+	expr, err = ParseExpr(`-2 ^ 2`)
+	assert.Nil(err)
+	assert.Equal("^", expr.(*UnaryExpr).Expr.(*BinaryExpr).Op)
+
+	expr, err = ParseExpr("2 ^ 3 ^ 2")
+	assert.Nil(err)
+	assert.Equal("^", expr.(*BinaryExpr).RHS.(*BinaryExpr).Op)
+}
+
+func TestParseExprErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, query := range []string{
+		``,
+		`sum(rate(foo[5m])`,
+		`foo{a="b"`,
+		`foo{a=b}`,
+		`foo{a=~"("}`,
+		`rate(foo[5m]`,
+		`sum(foo) by app`,
+		`foo and bool bar`,
+		`(foo + bar)[5m]`,
+		`foo[5x]`,
+		`foo offset 5m offset 1m`,
+		`{}`,
+		`{a=""}`,
+		`foo bar`,
+		`"unterminated`,
+		`foo / group_left bar`,
+		`foo ~ bar`,
+		`rate(foo[5m], 1)`,
+		`clamp(foo, 0)`,
+		`label_join(foo, "a")`,
+		`sum(foo) offset 5m`,
+		`foo @ 1 @ 2`,
+		`foo @ bar`,
+		`foo[5m:1m][1h]`,
+		`and(foo)`,
+	} {
+		_, err := ParseExpr(query)
+		assert.NotNil(err, query)
+	}
+}
+
+func TestParseExprFunctions(t *testing.T) {
+	assert := assert.New(t)
+
+	// Unknown functions are passed through, known ones have their number of arguments checked
+	expr, err := ParseExpr(`new_function(rate(foo[5m]))`)
+	assert.Nil(err)
+	assert.Equal("new_function", expr.(*Call).Func)
+	_, err = ParseExpr(`holt_winters(foo[1h], 0.5)`)
+	assert.NotNil(err)
+	assert.True(IsFunction("rate"))
+	assert.False(IsFunction("new_function"))
+	assert.True(IsAggregation("limit_ratio"))
+}
+
+func TestBuildExpr(t *testing.T) {
+	assert := assert.New(t)
+
+	rate := Func("rate", Range(Metric("foo", Selector{{Name: "app", Type: MatchEqual, Value: `x"}`}}), "5m"))
+	sum := Aggregate("sum", rate, []string{"le", "app"})
+	assert.Equal(`sum(rate(foo{app="x\"}"}[5m])) by (le,app)`, sum.String())
+
+	// Parentheses are added where precedence requires it
+	assert.Equal("(a + b) * c", Binary("*", Binary("+", Metric("a", nil), Metric("b", nil)), Metric("c", nil)).String())
+	assert.Equal("a - (b - c)", Binary("-", Metric("a", nil), Binary("-", Metric("b", nil), Metric("c", nil))).String())
+	assert.Equal("a / b or c", Binary("or", Binary("/", Metric("a", nil), Metric("b", nil)), Metric("c", nil)).String())
+	assert.Equal("round(a, 0.001)", Func("round", Metric("a", nil), Number(0.001)).String())
+}
+
+func TestParseSelector(t *testing.T) {
+	assert := assert.New(t)
+
+	selector, err := ParseSelector(`{namespace="ns",app!~"foo|bar",}`)
+	assert.Nil(err)
+	assert.Equal(Selector{{Name: "namespace", Type: MatchEqual, Value: "ns"}, {Name: "app", Type: MatchNotRegexp, Value: "foo|bar"}}, selector)
+
+	selector, err = ParseSelector("")
+	assert.Nil(err)
+	assert.Empty(selector)
+
+	_, err = ParseSelector(`{app="foo"} or vector(1)`)
+	assert.NotNil(err)
+}
@@ -0,0 +1,89 @@
+package promql
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/common/model"
+)
+
+// MatchType is the operator of a label matcher
+type MatchType string
+
+const (
+	MatchEqual     MatchType = "="
+	MatchNotEqual  MatchType = "!="
+	MatchRegexp    MatchType = "=~"
+	MatchNotRegexp MatchType = "!~"
+)
+
+// LabelMatcher matches series on a label value, see https://prometheus.io/docs/prometheus/latest/querying/basics/#time-series-selectors
+type LabelMatcher struct {
+	Name  string
+	Type  MatchType
+	Value string
+}
+
+// NewLabelMatcher returns a label matcher, or an error when the label name, the operator or the regular expression is invalid
+func NewLabelMatcher(name string, matchType MatchType, value string) (LabelMatcher, error) {
+	m := LabelMatcher{Name: name, Type: matchType, Value: value}
+	return m, m.Validate()
+}
+
+// Validate checks that the matcher can be safely rendered in a query
+func (m LabelMatcher) Validate() error {
+	if !model.LabelName(m.Name).IsValid() {
+		return fmt.Errorf("invalid label name '%s'", m.Name)
+	}
+	switch m.Type {
+	case MatchEqual, MatchNotEqual:
+	case MatchRegexp, MatchNotRegexp:
+		// Prometheus regular expressions are fully anchored RE2 expressions, like Go's
+		if _, err := regexp.Compile("^(?:" + m.Value + ")$"); err != nil {
+			return fmt.Errorf("invalid regular expression for label '%s': %v", m.Name, err)
+		}
+	default:
+		return fmt.Errorf("invalid operator '%s' for label '%s'", m.Type, m.Name)
+	}
+	return nil
+}
+
+// String renders the matcher, with the value quoted and escaped. Example: app=~"foo|bar"
+func (m LabelMatcher) String() string {
+	return m.Name + string(m.Type) + strconv.Quote(m.Value)
+}
+
+// Selector is a set of label matchers
+type Selector []LabelMatcher
+
+// String renders the matchers with braces, as expected after a metric name. Example: {namespace="ns",app!="foo"}
+func (s Selector) String() string {
+	matchers := make([]string, len(s))
+	for i, m := range s {
+		matchers[i] = m.String()
+	}
+	return "{" + strings.Join(matchers, ",") + "}"
+}
+
+// Validate checks every matcher of the selector
+func (s Selector) Validate() error {
+	for _, m := range s {
+		if err := m.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EqualMatchers returns equality matchers for the given labels, sorted by label name
+func EqualMatchers(labels map[string]string) []LabelMatcher {
+	matchers := make([]LabelMatcher, 0, len(labels))
+	for name, value := range labels {
+		matchers = append(matchers, LabelMatcher{Name: name, Type: MatchEqual, Value: value})
+	}
+	sort.Slice(matchers, func(i, j int) bool { return matchers[i].Name < matchers[j].Name })
+	return matchers
+}
@@ -0,0 +1,43 @@
+package promql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectorString(t *testing.T) {
+	assert := assert.New(t)
+
+	selector := Selector{
+		{Name: "namespace", Type: MatchEqual, Value: "ns"},
+		{Name: "app", Type: MatchNotEqual, Value: `foo"} or up{a="`},
+		{Name: "pod", Type: MatchRegexp, Value: `foo-\d+`},
+		{Name: "version", Type: MatchNotRegexp, Value: "v1|v2"},
+	}
+	assert.Nil(selector.Validate())
+	assert.Equal(`{namespace="ns",app!="foo\"} or up{a=\"",pod=~"foo-\\d+",version!~"v1|v2"}`, selector.String())
+	assert.Equal("{}", Selector{}.String())
+}
+
+func TestLabelMatcherValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := NewLabelMatcher("app", MatchRegexp, "foo.*")
+	assert.Nil(err)
+	_, err = NewLabelMatcher("app", MatchEqual, "(")
+	assert.Nil(err)
+	_, err = NewLabelMatcher("app", MatchRegexp, "(")
+	assert.NotNil(err)
+	_, err = NewLabelMatcher(`app="x"`, MatchEqual, "foo")
+	assert.NotNil(err)
+	_, err = NewLabelMatcher("app", "==", "foo")
+	assert.NotNil(err)
+}
+
+func TestEqualMatchers(t *testing.T) {
+	assert.Equal(t, []LabelMatcher{
+		{Name: "app", Type: MatchEqual, Value: "foo"},
+		{Name: "version", Type: MatchEqual, Value: "v1"},
+	}, EqualMatchers(map[string]string{"version": "v1", "app": "foo"}))
+}
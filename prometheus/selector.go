@@ -0,0 +1,31 @@
+package prometheus
+
+import (
+	"github.com/kiali/k-charted/prometheus/promql"
+)
+
+// LabelMatcher matches series on a label value, see promql.LabelMatcher
+type LabelMatcher = promql.LabelMatcher
+
+// MatchType is the operator of a label matcher
+type MatchType = promql.MatchType
+
+// Selector is a set of label matchers
+type Selector = promql.Selector
+
+const (
+	MatchEqual     = promql.MatchEqual
+	MatchNotEqual  = promql.MatchNotEqual
+	MatchRegexp    = promql.MatchRegexp
+	MatchNotRegexp = promql.MatchNotRegexp
+)
+
+// NewLabelMatcher returns a label matcher, or an error when the label name, the operator or the regular expression is invalid
+func NewLabelMatcher(name string, matchType MatchType, value string) (LabelMatcher, error) {
+	return promql.NewLabelMatcher(name, matchType, value)
+}
+
+// EqualMatchers returns equality matchers for the given labels, sorted by label name
+func EqualMatchers(labels map[string]string) []LabelMatcher {
+	return promql.EqualMatchers(labels)
+}
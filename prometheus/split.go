@@ -0,0 +1,106 @@
+package prometheus
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+// cacheMaxFreshness is how far from now results are considered incomplete, as recent samples may not be ingested yet.
+// Chunks ending within that duration are always queried, never cached.
+const cacheMaxFreshness = time.Minute
+
+// maxSplitChunks caps the number of chunks a range query is split into, as each chunk is fetched in its own goroutine.
+// Ranges spanning more chunks, such as a long range with a short split interval, are queried and cached as a whole.
+const maxSplitChunks = 50
+
+// fetchSplitRange splits a range query into chunks aligned on the split interval, so that historical chunks are cached and
+// only the newest ones are queried again on refresh, then stitches the results together
+func (in *Client) fetchSplitRange(ctx context.Context, query string, bounds v1.Range) Metric {
+	chunks := splitRange(bounds, in.config.CacheSplitInterval)
+	if len(chunks) > maxSplitChunks {
+		return in.cache.get(ctx, cacheKey(in.tenant, query, bounds), func(ctx context.Context) Metric {
+			return in.queryRange(ctx, query, bounds)
+		})
+	}
+	fresh := time.Now().Add(-cacheMaxFreshness)
+	results := make([]Metric, len(chunks))
+	wg := sync.WaitGroup{}
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func(result *Metric, chunk v1.Range) {
+			defer wg.Done()
+			if chunk.End.Before(fresh) {
+				*result = in.cache.get(ctx, cacheKey(in.tenant, query, chunk), func(ctx context.Context) Metric {
+					return in.queryRange(ctx, query, chunk)
+				})
+			} else {
+				*result = in.queryRange(ctx, query, chunk)
+			}
+		}(&results[i], chunk)
+	}
+	wg.Wait()
+
+	matrices := make([]model.Matrix, len(results))
+	for i, result := range results {
+		if result.Err != nil {
+			return result
+		}
+		matrices[i] = result.Matrix
+	}
+	return Metric{Matrix: mergeMatrices(matrices)}
+}
+
+// splitRange splits a step-aligned range into chunks bounded by multiples of the interval, rounded up to a multiple of step.
+// Chunks don't overlap: each one ends one step before the next one starts.
+func splitRange(bounds v1.Range, interval time.Duration) []v1.Range {
+	step := int64(bounds.Step.Seconds())
+	if step <= 0 {
+		return []v1.Range{bounds}
+	}
+	splitSecs := int64(interval.Seconds())
+	if splitSecs < step {
+		splitSecs = step
+	} else if splitSecs%step != 0 {
+		splitSecs += step - splitSecs%step
+	}
+	chunks := []v1.Range{}
+	end := bounds.End.Unix()
+	for start := bounds.Start.Unix(); start <= end; {
+		next := (start/splitSecs + 1) * splitSecs
+		chunkEnd := next - step
+		if chunkEnd > end {
+			chunkEnd = end
+		}
+		chunks = append(chunks, v1.Range{Start: time.Unix(start, 0), End: time.Unix(chunkEnd, 0), Step: bounds.Step})
+		start = next
+	}
+	return chunks
+}
+
+// mergeMatrices stitches the results of consecutive chunks, by series. Input series aren't modified, as they may be cached.
+func mergeMatrices(matrices []model.Matrix) model.Matrix {
+	if len(matrices) == 1 {
+		return matrices[0]
+	}
+	bySeries := make(map[model.Fingerprint]*model.SampleStream)
+	merged := model.Matrix{}
+	for _, matrix := range matrices {
+		for _, stream := range matrix {
+			fp := stream.Metric.Fingerprint()
+			target, ok := bySeries[fp]
+			if !ok {
+				target = &model.SampleStream{Metric: stream.Metric}
+				bySeries[fp] = target
+				merged = append(merged, target)
+			}
+			target.Values = append(target.Values, stream.Values...)
+		}
+	}
+	sort.Sort(merged)
+	return merged
+}
@@ -0,0 +1,141 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiali/k-charted/config/extconfig"
+)
+
+func TestSplitRange(t *testing.T) {
+	assert := assert.New(t)
+
+	step := 15 * time.Second
+	chunks := splitRange(v1.Range{Start: time.Unix(1005, 0), End: time.Unix(1800, 0), Step: step}, 5*time.Minute)
+	assert.Equal([]v1.Range{
+		{Start: time.Unix(1005, 0), End: time.Unix(1185, 0), Step: step},
+		{Start: time.Unix(1200, 0), End: time.Unix(1485, 0), Step: step},
+		{Start: time.Unix(1500, 0), End: time.Unix(1785, 0), Step: step},
+		{Start: time.Unix(1800, 0), End: time.Unix(1800, 0), Step: step},
+	}, chunks)
+
+	// Interval is rounded up to a multiple of step
+	chunks = splitRange(v1.Range{Start: time.Unix(0, 0), End: time.Unix(100, 0), Step: 30 * time.Second}, 50*time.Second)
+	assert.Equal([]v1.Range{
+		{Start: time.Unix(0, 0), End: time.Unix(30, 0), Step: 30 * time.Second},
+		{Start: time.Unix(60, 0), End: time.Unix(90, 0), Step: 30 * time.Second},
+	}, chunks)
+}
+
+func TestMergeMatrices(t *testing.T) {
+	assert := assert.New(t)
+
+	a := model.Metric{"app": "a"}
+	b := model.Metric{"app": "b"}
+	first := model.Matrix{
+		&model.SampleStream{Metric: b, Values: []model.SamplePair{{Timestamp: 0, Value: 1}}},
+		&model.SampleStream{Metric: a, Values: []model.SamplePair{{Timestamp: 0, Value: 2}}},
+	}
+	second := model.Matrix{
+		&model.SampleStream{Metric: a, Values: []model.SamplePair{{Timestamp: 15000, Value: 3}}},
+	}
+	merged := mergeMatrices([]model.Matrix{first, second})
+
+	assert.Len(merged, 2)
+	assert.Equal(a, merged[0].Metric)
+	assert.Equal([]model.SamplePair{{Timestamp: 0, Value: 2}, {Timestamp: 15000, Value: 3}}, merged[0].Values)
+	assert.Equal(b, merged[1].Metric)
+	// Inputs are untouched
+	assert.Len(first[1].Values, 1)
+}
+
+func TestIncrementalRangeCache(t *testing.T) {
+	assert := assert.New(t)
+
+	lock := sync.Mutex{}
+	var ranges [][2]int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start, _ := time.Parse(time.RFC3339Nano, r.FormValue("start"))
+		end, _ := time.Parse(time.RFC3339Nano, r.FormValue("end"))
+		step, _ := strconv.ParseFloat(r.FormValue("step"), 64)
+		lock.Lock()
+		ranges = append(ranges, [2]int64{start.Unix(), end.Unix()})
+		lock.Unlock()
+		values := [][]interface{}{}
+		for ts := start.Unix(); ts <= end.Unix(); ts += int64(step) {
+			values = append(values, []interface{}{ts, strconv.FormatInt(ts, 10)})
+		}
+		result := map[string]interface{}{
+			"status": "success",
+			"data": map[string]interface{}{
+				"resultType": "matrix",
+				"result":     []interface{}{map[string]interface{}{"metric": map[string]string{"app": "a"}, "values": values}},
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(result)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(extconfig.PrometheusConfig{URL: server.URL, CacheTTL: time.Hour, CacheSplitInterval: 10 * time.Minute})
+	assert.Nil(err)
+
+	q := MetricsQuery{}
+	q.FillDefaults()
+	metric := client.FetchRange(context.Background(), "my_gauge", "{}", "", "sum", &q)
+	assert.Nil(metric.Err)
+	assert.Len(metric.Matrix, 1)
+	bounds := alignRange(q.Range)
+	expected := int((bounds.End.Unix()-bounds.Start.Unix())/15) + 1
+	assert.Len(metric.Matrix[0].Values, expected)
+	for i, v := range metric.Matrix[0].Values {
+		assert.Equal(bounds.Start.Add(time.Duration(i)*15*time.Second).Unix(), v.Timestamp.Unix())
+	}
+	firstQueries := len(ranges)
+	assert.True(firstQueries >= 3)
+
+	// Refreshing only queries the recent chunks again
+	ranges = nil
+	metric = client.FetchRange(context.Background(), "my_gauge", "{}", "", "sum", &q)
+	assert.Nil(metric.Err)
+	assert.Len(metric.Matrix[0].Values, expected)
+	assert.True(len(ranges) < firstQueries)
+	for _, r := range ranges {
+		assert.True(r[1] >= time.Now().Add(-cacheMaxFreshness-5*time.Second).Unix())
+	}
+}
+
+func TestSplitRangeTooManyChunks(t *testing.T) {
+	assert := assert.New(t)
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[]}}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(extconfig.PrometheusConfig{URL: server.URL, CacheTTL: time.Hour, CacheSplitInterval: time.Second})
+	assert.Nil(err)
+
+	q := MetricsQuery{}
+	q.FillDefaults()
+	assert.True(len(splitRange(alignRange(q.Range), time.Second)) > maxSplitChunks)
+
+	// Too many chunks: the range is queried as a whole
+	metric := client.FetchRange(context.Background(), "my_gauge", "{}", "", "sum", &q)
+	assert.Nil(metric.Err)
+	assert.Equal(int32(1), requests)
+}
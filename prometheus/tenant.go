@@ -0,0 +1,71 @@
+package prometheus
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/kiali/k-charted/config/extconfig"
+)
+
+// DefaultTenantHeader is the header holding the tenant ID, as expected by Cortex and Mimir
+const DefaultTenantHeader = "X-Scope-OrgID"
+
+type tenantKey struct{}
+
+func withTenant(ctx context.Context, tenant string) context.Context {
+	if tenant == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// tenantFor returns the tenant of a namespace, falling back to the default tenant
+func tenantFor(cfg extconfig.PrometheusConfig, namespace string) string {
+	if tenant, ok := cfg.NamespaceTenants[namespace]; ok {
+		return tenant
+	}
+	return cfg.Tenant
+}
+
+// tenantRoundTripper sets the tenant header of each request, from the request context, and adds backend specific query parameters
+type tenantRoundTripper struct {
+	header     string
+	params     url.Values
+	originalRT http.RoundTripper
+}
+
+func newTenantRoundTripper(cfg extconfig.PrometheusConfig, rt http.RoundTripper) http.RoundTripper {
+	params := url.Values{}
+	if cfg.PartialResponse != nil {
+		params.Set("partial_response", strconv.FormatBool(*cfg.PartialResponse))
+	}
+	if cfg.Dedup != nil {
+		params.Set("dedup", strconv.FormatBool(*cfg.Dedup))
+	}
+	if cfg.Tenant == "" && len(cfg.NamespaceTenants) == 0 && len(params) == 0 {
+		return rt
+	}
+	header := cfg.TenantHeader
+	if header == "" {
+		header = DefaultTenantHeader
+	}
+	return &tenantRoundTripper{header: header, params: params, originalRT: rt}
+}
+
+func (rt *tenantRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Round trippers must not modify the original request
+	req = req.Clone(req.Context())
+	if tenant, ok := req.Context().Value(tenantKey{}).(string); ok {
+		req.Header.Set(rt.header, tenant)
+	}
+	if len(rt.params) > 0 {
+		q := req.URL.Query()
+		for k := range rt.params {
+			q.Set(k, rt.params.Get(k))
+		}
+		req.URL.RawQuery = q.Encode()
+	}
+	return rt.originalRT.RoundTrip(req)
+}
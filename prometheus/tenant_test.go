@@ -0,0 +1,51 @@
+package prometheus
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiali/k-charted/config/extconfig"
+)
+
+func TestTenantHeaderAndParams(t *testing.T) {
+	assert := assert.New(t)
+
+	var tenants, partialResponses, dedups []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenants = append(tenants, r.Header.Get("X-Scope-OrgID"))
+		partialResponses = append(partialResponses, r.FormValue("partial_response"))
+		dedups = append(dedups, r.FormValue("dedup"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[]}}`))
+	}))
+	defer server.Close()
+
+	partialResponse := false
+	client, err := NewClient(extconfig.PrometheusConfig{
+		URL:              server.URL,
+		Tenant:           "default-tenant",
+		NamespaceTenants: map[string]string{"ns1": "tenant1"},
+		PartialResponse:  &partialResponse,
+	})
+	assert.Nil(err)
+
+	q := MetricsQuery{}
+	q.FillDefaults()
+	for _, c := range []ClientInterface{client, client.ForNamespace("ns1"), client.ForNamespace("ns2")} {
+		metric := c.FetchRateRange(context.Background(), "my_counter", "{}", "", &q)
+		assert.Nil(metric.Err)
+	}
+
+	assert.Equal([]string{"default-tenant", "tenant1", "default-tenant"}, tenants)
+	assert.Equal([]string{"false", "false", "false"}, partialResponses)
+	assert.Equal([]string{"", "", ""}, dedups)
+}
+
+func TestNoTenant(t *testing.T) {
+	rt := newTenantRoundTripper(extconfig.PrometheusConfig{}, http.DefaultTransport)
+	assert.Equal(t, http.DefaultTransport, rt)
+}
@@ -15,8 +15,15 @@ type MetricsQuery struct {
 	Quantiles    []string
 	Avg          bool
 	ByLabels     []string
+	// Rounding is the precision of values rounded in queries, DefaultRounding when nil. Zero disables rounding in queries.
+	Rounding *float64
+	// Instant makes queries evaluated at End only, with results as vectors rather than matrices
+	Instant bool
 }
 
+// DefaultRounding is the precision of values rounded in queries, unless otherwise requested
+const DefaultRounding = 0.001
+
 // FillDefaults fills the struct with default parameters
 func (q *MetricsQuery) FillDefaults() {
 	q.End = time.Now()
@@ -36,7 +43,11 @@ type Metrics struct {
 // Metric holds the Prometheus Matrix model, which contains one or more time series (depending on grouping)
 type Metric struct {
 	Matrix model.Matrix `json:"matrix"`
+	// Vector is the result of instant queries
+	Vector model.Vector `json:"vector,omitempty"`
 	Err    error
+	// Query is the PromQL query sent to Prometheus
+	Query string `json:"query,omitempty"`
 }
 
 // Histogram contains Metric objects for several histogram-kind statistics
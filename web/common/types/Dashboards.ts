@@ -1,15 +1,17 @@
-import { TimeSeries } from './Metrics';
+import { Sample, TimeSeries } from './Metrics';
 import { PromLabel, LabelDisplayName } from './Labels';
 
 export interface DashboardModel {
   title: string;
+  source?: string;
   charts: ChartModel[];
   aggregations: AggregationModel[];
   externalLinks: ExternalLink[];
+  variables?: Variable[];
 }
 
 export type SpanValue = 1 | 2 | 3 | 4 | 5 | 6 | 7 | 8 | 9 | 10 | 11 | 12;
-export type ChartType = 'area' | 'line' | 'bar' | 'scatter';
+export type ChartType = 'area' | 'line' | 'bar' | 'scatter' | 'heatmap' | 'stat' | 'gauge' | 'table' | 'bar-by-label';
 export type XAxisType = 'time' | 'series';
 
 export interface ChartModel {
@@ -20,11 +22,22 @@ export interface ChartModel {
   min?: number;
   max?: number;
   metrics: TimeSeries[];
+  heatmaps?: Heatmap[];
+  vector?: Sample[];
   error?: string;
+  queries?: string[];
   startCollapsed: boolean;
   xAxis?: XAxisType;
 }
 
+// Heatmap holds per-bucket values, indexed by bucket then timestamp
+export interface Heatmap {
+  labelSet: { [key: string]: string };
+  buckets: string[];
+  timestamps: number[];
+  values: number[][];
+}
+
 export interface AggregationModel {
   label: PromLabel;
   displayName: LabelDisplayName;
@@ -44,3 +57,10 @@ export interface ExternalLinkVariables {
   version?: string;
   workload?: string;
 }
+
+export interface Variable {
+  name: string;
+  displayName: string;
+  options: string[];
+  value: string;
+}
@@ -8,6 +8,12 @@ export interface TimeSeries {
   values: Datapoint[];
 }
 
+// Sample is the value of a series at a single time, for instant charts
+export interface Sample {
+  labelSet: LabelSet;
+  value: Datapoint;
+}
+
 export interface NamedTimeSeries extends TimeSeries {
   name: string;
 }
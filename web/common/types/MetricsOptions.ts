@@ -13,6 +13,7 @@ export interface DashboardQuery extends MetricsQuery {
   rawDataAggregator?: Aggregator;
   labelsFilters?: string;
   additionalLabels?: string;
+  maxDataPoints?: number;
 }
 
 export type Aggregator = 'sum' | 'avg' | 'min' | 'max' | 'stddev' | 'stdvar';